@@ -0,0 +1,277 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// 请求体类型
+const (
+	BodyForm      = "form"
+	BodyJSON      = "json"
+	BodyMultipart = "multipart"
+	BodyXML       = "xml"
+	BodyRaw       = "raw"
+)
+
+// multipart 中非文件字段值的最大保留长度
+const maxPartValueBytes = 1024
+
+// RequestBody 解析后的请求体
+type RequestBody struct {
+	Kind   string              `json:"kind"`
+	Form   map[string][]string `json:"form,omitempty"`
+	JSON   interface{}         `json:"json,omitempty"`
+	Parts  []MultipartPart     `json:"parts,omitempty"`
+	XML    *XMLNode            `json:"xml,omitempty"`
+	Params []BodyParam         `json:"params,omitempty"` // 可注入的参数列表
+}
+
+// MultipartPart multipart 请求体中的单个部分
+type MultipartPart struct {
+	Name        string `json:"name"`
+	Filename    string `json:"filename,omitempty"`
+	ContentType string `json:"content_type,omitempty"`
+	Value       string `json:"value,omitempty"` // 文件内容不保留
+	Size        int    `json:"size"`
+}
+
+// XMLNode XML 元素节点
+type XMLNode struct {
+	Name     string            `json:"name"`
+	Attrs    map[string]string `json:"attrs,omitempty"`
+	Text     string            `json:"text,omitempty"`
+	Children []*XMLNode        `json:"children,omitempty"`
+}
+
+// BodyParam 请求体中的参数
+// Name 对于 JSON 为 "a.b[].c"，对于 XML 为 "root/child/@attr"
+type BodyParam struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+	Type  string `json:"type"`
+	File  bool   `json:"file,omitempty"`
+}
+
+// HasFile 请求体是否包含文件上传
+func (b *RequestBody) HasFile() bool {
+	if b == nil {
+		return false
+	}
+	for _, part := range b.Parts {
+		if part.Filename != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// decodeBody 按 Content-Type 解析请求体，无法识别时返回 raw 类型
+func decodeBody(contentType string, data []byte) *RequestBody {
+	if len(data) == 0 {
+		return nil
+	}
+
+	mediaType, params, _ := mime.ParseMediaType(contentType)
+	mediaType = strings.ToLower(mediaType)
+
+	var body *RequestBody
+	var err error
+	switch {
+	case mediaType == "application/x-www-form-urlencoded":
+		body, err = decodeFormBody(data)
+	case mediaType == "multipart/form-data":
+		body, err = decodeMultipartBody(data, params["boundary"])
+	case strings.HasSuffix(mediaType, "json"):
+		body, err = decodeJSONBody(data)
+	case strings.HasSuffix(mediaType, "xml"):
+		body, err = decodeXMLBody(data)
+	case mediaType == "":
+		// 未声明类型时依次尝试 JSON 和表单
+		if json.Valid(data) {
+			body, err = decodeJSONBody(data)
+		} else {
+			body, err = decodeFormBody(data)
+		}
+	default:
+		err = errors.New("unsupported content type")
+	}
+
+	if err != nil || body == nil {
+		return &RequestBody{Kind: BodyRaw}
+	}
+	return body
+}
+
+// decodeFormBody 解析 urlencoded 请求体
+func decodeFormBody(data []byte) (*RequestBody, error) {
+	values, err := url.ParseQuery(string(data))
+	if err != nil || len(values) == 0 {
+		return nil, errors.New("invalid form body")
+	}
+
+	body := &RequestBody{Kind: BodyForm, Form: values}
+	names := make([]string, 0, len(values))
+	for name := range values {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		for _, value := range values[name] {
+			body.Params = append(body.Params, BodyParam{Name: name, Value: value, Type: inferValueType(value)})
+		}
+	}
+	return body, nil
+}
+
+// decodeJSONBody 解析 JSON 请求体
+func decodeJSONBody(data []byte) (*RequestBody, error) {
+	var tree interface{}
+	if err := json.Unmarshal(data, &tree); err != nil {
+		return nil, err
+	}
+
+	body := &RequestBody{Kind: BodyJSON, JSON: tree}
+	flattenJSON("", tree, func(name, sample, typ string) {
+		body.Params = append(body.Params, BodyParam{Name: name, Value: sample, Type: typ})
+	})
+	sort.SliceStable(body.Params, func(i, j int) bool {
+		return body.Params[i].Name < body.Params[j].Name
+	})
+	return body, nil
+}
+
+// decodeMultipartBody 解析 multipart 请求体
+func decodeMultipartBody(data []byte, boundary string) (*RequestBody, error) {
+	if boundary == "" {
+		return nil, errors.New("missing multipart boundary")
+	}
+
+	body := &RequestBody{Kind: BodyMultipart}
+	reader := multipart.NewReader(bytes.NewReader(data), boundary)
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			// 截断的请求体（如浏览器未提供文件内容）保留已解析部分
+			break
+		}
+		content, _ := io.ReadAll(part)
+		p := MultipartPart{
+			Name:        part.FormName(),
+			Filename:    part.FileName(),
+			ContentType: part.Header.Get("Content-Type"),
+			Size:        len(content),
+		}
+		param := BodyParam{Name: p.Name, File: p.Filename != ""}
+		if p.Filename == "" {
+			p.Value = truncateUTF8(string(content), maxPartValueBytes)
+			param.Value = p.Value
+			param.Type = inferValueType(p.Value)
+		} else {
+			param.Value = p.Filename
+			param.Type = "file"
+		}
+		body.Parts = append(body.Parts, p)
+		body.Params = append(body.Params, param)
+	}
+
+	if len(body.Parts) == 0 {
+		return nil, errors.New("empty multipart body")
+	}
+	return body, nil
+}
+
+// decodeXMLBody 解析 XML 请求体
+func decodeXMLBody(data []byte) (*RequestBody, error) {
+	decoder := xml.NewDecoder(bytes.NewReader(data))
+	var stack []*XMLNode
+	var root *XMLNode
+
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		switch t := token.(type) {
+		case xml.StartElement:
+			node := &XMLNode{Name: t.Name.Local}
+			for _, attr := range t.Attr {
+				if node.Attrs == nil {
+					node.Attrs = make(map[string]string)
+				}
+				node.Attrs[attr.Name.Local] = attr.Value
+			}
+			if len(stack) > 0 {
+				parent := stack[len(stack)-1]
+				parent.Children = append(parent.Children, node)
+			} else if root == nil {
+				root = node
+			}
+			stack = append(stack, node)
+		case xml.EndElement:
+			if len(stack) > 0 {
+				stack = stack[:len(stack)-1]
+			}
+		case xml.CharData:
+			if len(stack) > 0 {
+				stack[len(stack)-1].Text += strings.TrimSpace(string(t))
+			}
+		}
+	}
+
+	if root == nil {
+		return nil, errors.New("empty xml body")
+	}
+	body := &RequestBody{Kind: BodyXML, XML: root}
+	collectXMLParams(root, root.Name, &body.Params)
+	return body, nil
+}
+
+// collectXMLParams 收集 XML 叶子节点和属性作为参数
+func collectXMLParams(node *XMLNode, path string, params *[]BodyParam) {
+	attrNames := make([]string, 0, len(node.Attrs))
+	for name := range node.Attrs {
+		attrNames = append(attrNames, name)
+	}
+	sort.Strings(attrNames)
+	for _, name := range attrNames {
+		value := node.Attrs[name]
+		*params = append(*params, BodyParam{Name: path + "/@" + name, Value: value, Type: inferValueType(value)})
+	}
+
+	if len(node.Children) == 0 {
+		*params = append(*params, BodyParam{Name: path, Value: node.Text, Type: inferValueType(node.Text)})
+		return
+	}
+
+	// 同名兄弟节点使用下标区分
+	counts := make(map[string]int)
+	for _, child := range node.Children {
+		counts[child.Name]++
+	}
+	seen := make(map[string]int)
+	for _, child := range node.Children {
+		childPath := path + "/" + child.Name
+		if counts[child.Name] > 1 {
+			childPath += "[" + strconv.Itoa(seen[child.Name]) + "]"
+			seen[child.Name]++
+		}
+		collectXMLParams(child, childPath, params)
+	}
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestDecodeBody(t *testing.T) {
+	multipartBody := "--X\r\n" +
+		"Content-Disposition: form-data; name=\"title\"\r\n\r\nhello\r\n" +
+		"--X\r\n" +
+		"Content-Disposition: form-data; name=\"avatar\"; filename=\"a.png\"\r\nContent-Type: image/png\r\n\r\nPNGDATA\r\n" +
+		"--X--\r\n"
+	tests := []struct {
+		name        string
+		contentType string
+		data        string
+		wantKind    string
+		wantParams  []string
+		wantFile    bool
+	}{
+		{"empty", "application/json", "", "", nil, false},
+		{"form", "application/x-www-form-urlencoded; charset=UTF-8", "a=1&b=x", BodyForm, []string{"a", "b"}, false},
+		{"json", "application/json", `{"user":{"id":1,"tags":["x"]}}`, BodyJSON, []string{"user.id", "user.tags[]"}, false},
+		{"vendor json", "application/vnd.api+json", `{"a":true}`, BodyJSON, []string{"a"}, false},
+		{"xml", "text/xml", `<root id="1"><name>x</name></root>`, BodyXML, []string{"root/@id", "root/name"}, false},
+		{"multipart", "multipart/form-data; boundary=X", multipartBody, BodyMultipart, []string{"avatar", "title"}, true},
+		{"untyped json", "", `{"a":1}`, BodyJSON, []string{"a"}, false},
+		{"untyped form", "", "a=1", BodyForm, []string{"a"}, false},
+		{"invalid json", "application/json", `{"a":`, BodyRaw, nil, false},
+		{"binary", "application/octet-stream", "\x00\x01", BodyRaw, nil, false},
+	}
+	for _, tt := range tests {
+		body := decodeBody(tt.contentType, []byte(tt.data))
+		if tt.wantKind == "" {
+			if body != nil {
+				t.Errorf("%s: decodeBody() = %+v, want nil", tt.name, body)
+			}
+			continue
+		}
+		if body == nil || body.Kind != tt.wantKind {
+			t.Errorf("%s: decodeBody() = %+v, want kind %s", tt.name, body, tt.wantKind)
+			continue
+		}
+		names := make(map[string]bool)
+		for _, param := range body.Params {
+			names[param.Name] = true
+		}
+		if len(names) != len(tt.wantParams) {
+			t.Errorf("%s: params = %v, want %v", tt.name, body.Params, tt.wantParams)
+		}
+		for _, name := range tt.wantParams {
+			if !names[name] {
+				t.Errorf("%s: missing param %s in %v", tt.name, name, body.Params)
+			}
+		}
+		if body.HasFile() != tt.wantFile {
+			t.Errorf("%s: HasFile() = %v, want %v", tt.name, body.HasFile(), tt.wantFile)
+		}
+	}
+}
+
+func TestDecodeMultipartTruncatesUTF8(t *testing.T) {
+	// 多字节字符跨越截断位置时不能截出无效的 UTF-8
+	value := strings.Repeat("a", maxPartValueBytes-1) + strings.Repeat("中", 10)
+	data := "--X\r\nContent-Disposition: form-data; name=\"note\"\r\n\r\n" + value + "\r\n--X--\r\n"
+	body := decodeBody("multipart/form-data; boundary=X", []byte(data))
+	if body == nil || len(body.Parts) != 1 {
+		t.Fatalf("decodeBody() = %+v", body)
+	}
+	got := body.Parts[0].Value
+	if !utf8.ValidString(got) || got != strings.Repeat("a", maxPartValueBytes-1) {
+		t.Errorf("part value = %q (%d bytes), want %d ASCII bytes", got, len(got), maxPartValueBytes-1)
+	}
+	if body.Parts[0].Size != len(value) {
+		t.Errorf("part size = %d, want %d", body.Parts[0].Size, len(value))
+	}
+}
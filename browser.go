@@ -0,0 +1,93 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/chromedp/cdproto/target"
+	"github.com/chromedp/chromedp"
+)
+
+// 浏览器上下文隔离方式
+const (
+	IsolationShared = "shared" // 所有标签页共享浏览器上下文（多角色爬取时每个角色仍使用独立上下文）
+	IsolationRole   = "role"   // 每次爬取（或每个角色）使用独立的隐身浏览器上下文
+	IsolationTab    = "tab"    // 每个标签页使用独立的隐身浏览器上下文
+)
+
+// validateIsolation 校验浏览器上下文隔离参数
+func validateIsolation(isolation, seed string) error {
+	switch isolation {
+	case IsolationShared, IsolationRole, IsolationTab:
+	default:
+		return fmt.Errorf("invalid isolation %q, expected shared, role or tab", isolation)
+	}
+	switch seed {
+	case "session", "none":
+	default:
+		return fmt.Errorf("invalid isolation seed %q, expected session or none", seed)
+	}
+	return nil
+}
+
+// parseChromeFlag 解析额外的浏览器启动参数："name"、"name=value" 或 "name=false"（移除默认参数），可带 "--" 前缀
+func parseChromeFlag(raw string) (string, interface{}, error) {
+	raw = strings.TrimLeft(strings.TrimSpace(raw), "-")
+	name, value, found := strings.Cut(raw, "=")
+	if name == "" {
+		return "", nil, fmt.Errorf("invalid chrome flag %q", raw)
+	}
+	switch {
+	case !found || value == "true":
+		return name, true, nil
+	case value == "false":
+		return name, false, nil
+	}
+	return name, value, nil
+}
+
+// resolveExtensionPaths 校验解压后的扩展目录（须包含 manifest.json）并转换为绝对路径
+func resolveExtensionPaths(paths []string) ([]string, error) {
+	result := make([]string, 0, len(paths))
+	for _, path := range paths {
+		abs, err := filepath.Abs(path)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := os.Stat(filepath.Join(abs, "manifest.json")); err != nil {
+			return nil, fmt.Errorf("invalid extension %s: manifest.json not found, expected an unpacked extension directory", path)
+		}
+		result = append(result, abs)
+	}
+	return result, nil
+}
+
+// profileOptions 浏览器配置文件、扩展和额外启动参数，额外参数最后追加，可覆盖默认参数
+func profileOptions(conf *BrowserConfig) []chromedp.ExecAllocatorOption {
+	opts := make([]chromedp.ExecAllocatorOption, 0)
+	if conf.UserDataDir != "" {
+		opts = append(opts, chromedp.UserDataDir(conf.UserDataDir))
+	}
+	if len(conf.Extensions) > 0 {
+		extensions := strings.Join(conf.Extensions, ",")
+		opts = append(opts,
+			chromedp.Flag("disable-extensions", false),
+			chromedp.Flag("disable-extensions-except", extensions),
+			chromedp.Flag("load-extension", extensions),
+		)
+	}
+	for _, flag := range conf.ExtraFlags {
+		// 已在加载参数时校验
+		if name, value, err := parseChromeFlag(flag); err == nil {
+			opts = append(opts, chromedp.Flag(name, value))
+		}
+	}
+	return opts
+}
+
+// isCrawlTarget 判断目标是否为可爬取的页面，扩展的 service worker、后台页和扩展页面不参与爬取
+func isCrawlTarget(info *target.Info) bool {
+	return info != nil && info.Type == "page" && !strings.HasPrefix(info.URL, "chrome-extension://")
+}
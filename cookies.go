@@ -0,0 +1,215 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/chromedp/cdproto/network"
+)
+
+// jsonCookie 浏览器扩展（如 EditThisCookie、Cookie-Editor）、Playwright 和 CDP 导出的 cookie
+type jsonCookie struct {
+	Name           string   `json:"name"`
+	Value          string   `json:"value"`
+	Domain         string   `json:"domain"`
+	Path           string   `json:"path"`
+	Secure         bool     `json:"secure"`
+	HTTPOnly       bool     `json:"httpOnly"`
+	HostOnly       bool     `json:"hostOnly"`
+	SameSite       string   `json:"sameSite"`
+	ExpirationDate *float64 `json:"expirationDate"`
+	Expires        *float64 `json:"expires"`
+}
+
+// storageEntry 单个源的 Web Storage
+type storageEntry struct {
+	LocalStorage   map[string]string `json:"localStorage"`
+	SessionStorage map[string]string `json:"sessionStorage"`
+}
+
+// loadCookieFile 加载 Netscape cookies.txt 或 JSON 格式的 cookie 文件，已过期的 cookie 会被忽略
+func loadCookieFile(path string) ([]*network.Cookie, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var cookies []*network.Cookie
+	trimmed := bytes.TrimSpace(content)
+	if bytes.HasPrefix(trimmed, []byte("[")) || bytes.HasPrefix(trimmed, []byte("{")) {
+		cookies, err = parseJSONCookies(trimmed)
+	} else {
+		cookies, err = parseNetscapeCookies(trimmed)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("parse %s: %w", path, err)
+	}
+
+	now := float64(time.Now().Unix())
+	result := make([]*network.Cookie, 0, len(cookies))
+	for _, c := range cookies {
+		if c.Name == "" || c.Domain == "" || (!c.Session && c.Expires < now) {
+			continue
+		}
+		result = append(result, c)
+	}
+	return result, nil
+}
+
+// parseNetscapeCookies 解析 Netscape cookies.txt（curl、wget 和浏览器扩展导出的格式）
+func parseNetscapeCookies(content []byte) ([]*network.Cookie, error) {
+	cookies := make([]*network.Cookie, 0)
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := strings.TrimSpace(scanner.Text())
+		httpOnly := false
+		if strings.HasPrefix(text, "#HttpOnly_") {
+			httpOnly = true
+			text = strings.TrimPrefix(text, "#HttpOnly_")
+		}
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+
+		// domain, include subdomains, path, secure, expires, name, value
+		fields := strings.Split(text, "\t")
+		if len(fields) < 6 {
+			return nil, fmt.Errorf("line %d: expected 7 tab-separated fields", line)
+		}
+		if len(fields) == 6 {
+			fields = append(fields, "")
+		}
+		expires, err := strconv.ParseFloat(fields[4], 64)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: invalid expires: %w", line, err)
+		}
+
+		domain := fields[0]
+		if strings.EqualFold(fields[1], "TRUE") && !strings.HasPrefix(domain, ".") {
+			domain = "." + domain
+		}
+		cookies = append(cookies, &network.Cookie{
+			Name:     fields[5],
+			Value:    fields[6],
+			Domain:   domain,
+			Path:     fields[2],
+			Secure:   strings.EqualFold(fields[3], "TRUE"),
+			HTTPOnly: httpOnly,
+			Expires:  expires,
+			Session:  expires == 0,
+		})
+	}
+	return cookies, scanner.Err()
+}
+
+// parseJSONCookies 解析 JSON 格式的 cookie：cookie 数组，或带 cookies 字段的对象（Playwright storageState）
+func parseJSONCookies(content []byte) ([]*network.Cookie, error) {
+	var items []jsonCookie
+	if bytes.HasPrefix(content, []byte("{")) {
+		var state struct {
+			Cookies []jsonCookie `json:"cookies"`
+		}
+		if err := json.Unmarshal(content, &state); err != nil {
+			return nil, err
+		}
+		items = state.Cookies
+	} else if err := json.Unmarshal(content, &items); err != nil {
+		return nil, err
+	}
+
+	cookies := make([]*network.Cookie, 0, len(items))
+	for _, item := range items {
+		c := &network.Cookie{
+			Name:     item.Name,
+			Value:    item.Value,
+			Domain:   item.Domain,
+			Path:     item.Path,
+			Secure:   item.Secure,
+			HTTPOnly: item.HTTPOnly,
+			SameSite: parseSameSite(item.SameSite),
+			Session:  true,
+		}
+		if item.HostOnly {
+			c.Domain = strings.TrimPrefix(c.Domain, ".")
+		}
+		for _, expires := range []*float64{item.ExpirationDate, item.Expires} {
+			if expires != nil && *expires > 0 {
+				c.Expires = *expires
+				c.Session = false
+			}
+		}
+		cookies = append(cookies, c)
+	}
+	return cookies, nil
+}
+
+// parseSameSite 统一不同导出工具的 SameSite 取值
+func parseSameSite(value string) network.CookieSameSite {
+	switch strings.ToLower(value) {
+	case "strict":
+		return network.CookieSameSiteStrict
+	case "lax":
+		return network.CookieSameSiteLax
+	case "none", "no_restriction":
+		return network.CookieSameSiteNone
+	}
+	return ""
+}
+
+// parseCookieHeader 将 -cookie 参数转换为入口 URL 主机的 host-only cookie
+func parseCookieHeader(header, entranceURL string) []*network.Cookie {
+	if header == "" {
+		return nil
+	}
+	u, err := url.Parse(entranceURL)
+	if err != nil || u.Hostname() == "" {
+		return nil
+	}
+	cookies := make([]*network.Cookie, 0)
+	for _, pair := range strings.Split(header, ";") {
+		name, value, found := strings.Cut(strings.TrimSpace(pair), "=")
+		if !found || name == "" {
+			continue
+		}
+		cookies = append(cookies, &network.Cookie{
+			Name:    name,
+			Value:   value,
+			Domain:  u.Hostname(),
+			Path:    "/",
+			Session: true,
+		})
+	}
+	return cookies
+}
+
+// loadStorageFile 加载 Web Storage 文件，格式：{"https://origin": {"localStorage": {...}, "sessionStorage": {...}}}
+func loadStorageFile(path string) (map[string]storageEntry, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var entries map[string]storageEntry
+	if err := json.Unmarshal(content, &entries); err != nil {
+		return nil, fmt.Errorf("parse %s: %w", path, err)
+	}
+	// 统一为 location.origin 的格式
+	result := make(map[string]storageEntry, len(entries))
+	for origin, entry := range entries {
+		u, err := url.Parse(origin)
+		if err != nil || u.Scheme == "" || u.Host == "" || (u.Path != "" && u.Path != "/") {
+			return nil, errors.New("invalid storage origin: " + origin)
+		}
+		result[strings.ToLower(u.Scheme+"://"+u.Host)] = entry
+	}
+	return result, nil
+}
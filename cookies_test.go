@@ -0,0 +1,41 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestParseNetscapeCookies(t *testing.T) {
+	content := "# Netscape HTTP Cookie File\n" +
+		"\n" +
+		"example.com\tTRUE\t/\tTRUE\t1999999999\tsid\tabc\n" +
+		"#HttpOnly_app.example.com\tFALSE\t/api\tFALSE\t0\ttoken\tx=y\n" +
+		".example.org\tTRUE\t/\tFALSE\t1999999999\tempty\n"
+	cookies, err := parseNetscapeCookies([]byte(content))
+	if err != nil {
+		t.Fatalf("parseNetscapeCookies() error = %v", err)
+	}
+	tests := []struct {
+		name, value, domain, path string
+		secure, httpOnly, session bool
+	}{
+		{"sid", "abc", ".example.com", "/", true, false, false},
+		{"token", "x=y", "app.example.com", "/api", false, true, true},
+		{"empty", "", ".example.org", "/", false, false, false},
+	}
+	if len(cookies) != len(tests) {
+		t.Fatalf("parseNetscapeCookies() returned %d cookies, want %d", len(cookies), len(tests))
+	}
+	for i, tt := range tests {
+		c := cookies[i]
+		if c.Name != tt.name || c.Value != tt.value || c.Domain != tt.domain || c.Path != tt.path ||
+			c.Secure != tt.secure || c.HTTPOnly != tt.httpOnly || c.Session != tt.session {
+			t.Errorf("cookie %d = %+v, want %+v", i, *c, tt)
+		}
+	}
+
+	for _, invalid := range []string{"example.com\tTRUE\t/\tTRUE\n", "example.com\tTRUE\t/\tTRUE\tnever\tsid\tabc\n"} {
+		if _, err := parseNetscapeCookies([]byte(invalid)); err == nil {
+			t.Errorf("parseNetscapeCookies(%q) error = nil, want error", invalid)
+		}
+	}
+}
@@ -25,8 +25,11 @@ import (
 const bindingName = "sendLink"
 
 type bindingPayload struct {
-	URL    string `json:"url"`
-	Source string `json:"source"`
+	URL     string            `json:"url"`
+	Source  string            `json:"source"`
+	Params  []string          `json:"params,omitempty"`
+	Tokens  []tokenField      `json:"tokens,omitempty"`
+	Message *postMessageEvent `json:"message,omitempty"`
 }
 
 // AdaptiveConcurrency 动态并发控制
@@ -104,22 +107,6 @@ func (ac *AdaptiveConcurrency) GetCurrent() int {
 	return ac.current
 }
 
-// httpClient 包级别的 HTTP 客户端，用于重定向响应的链接提取，复用连接池
-var httpClient = &http.Client{
-	CheckRedirect: func(req *http.Request, via []*http.Request) error {
-		return http.ErrUseLastResponse
-	},
-	Transport: &http.Transport{
-		MaxIdleConns:        200,
-		MaxIdleConnsPerHost: 20,
-		MaxConnsPerHost:     50,
-		IdleConnTimeout:     120 * time.Second,
-		DisableKeepAlives:   false,
-		ForceAttemptHTTP2:   true,
-	},
-	Timeout: 15 * time.Second,
-}
-
 // 资源类型判断 map，避免每次创建切片和遍历
 var (
 	// 需要丢弃的资源类型（不影响 DOM 结构的静态资源）
@@ -148,17 +135,45 @@ var (
 
 // CrawlerState 爬虫状态管理
 type CrawlerState struct {
-	mu      sync.RWMutex
-	visited map[string]bool // 使用 map 替代 slice，提升查找效率
+	mu         sync.RWMutex
+	visited    map[string]bool // 使用 map 替代 slice，提升查找效率
+	workers    *workerRegistry // 本次爬取附加的 Worker
+	done       <-chan struct{} // 爬取结束时关闭
+	background sync.WaitGroup  // 后台任务，爬取结束时等待完成
 }
 
-// NewCrawlerState 创建新的爬虫状态
-func NewCrawlerState() *CrawlerState {
+// NewCrawlerState 创建新的爬虫状态，done 关闭后请求不再入队
+func NewCrawlerState(done <-chan struct{}) *CrawlerState {
 	return &CrawlerState{
 		visited: make(map[string]bool),
+		done:    done,
 	}
 }
 
+// Enqueue 将请求加入队列，队列满时等待，爬取结束后放弃并返回 false
+func (cs *CrawlerState) Enqueue(reqC chan request, req request) bool {
+	select {
+	case reqC <- req:
+		return true
+	case <-cs.done:
+		return false
+	}
+}
+
+// Go 在后台执行任务
+func (cs *CrawlerState) Go(fn func()) {
+	cs.background.Add(1)
+	go func() {
+		defer cs.background.Done()
+		fn()
+	}()
+}
+
+// Wait 等待后台任务完成
+func (cs *CrawlerState) Wait() {
+	cs.background.Wait()
+}
+
 // IsVisited 检查 URL 是否已访问
 func (cs *CrawlerState) IsVisited(key string) bool {
 	cs.mu.RLock()
@@ -228,6 +243,33 @@ func (p *EventWorkerPool) Close() {
 }
 
 func initBrowser(conf *BrowserConfig) (context.Context, context.CancelFunc) {
+	// 连接已运行的浏览器，启动参数由该浏览器自身决定
+	if conf.RemoteURL != "" {
+		ignored := make([]string, 0)
+		if conf.Headless {
+			ignored = append(ignored, "-gui")
+		}
+		if conf.ChromiumPath != "" {
+			ignored = append(ignored, "-chromium_path")
+		}
+		if conf.Proxy != nil {
+			ignored = append(ignored, "-proxy")
+		}
+		if conf.TLS != nil && len(conf.TLS.BrowserOptions()) > 0 {
+			ignored = append(ignored, "-ignore_cert_errors/-ca_cert")
+		}
+		if len(conf.Resolve) > 0 {
+			ignored = append(ignored, "-resolve")
+		}
+		if conf.UserDataDir != "" || len(conf.Extensions) > 0 || len(conf.ExtraFlags) > 0 {
+			ignored = append(ignored, "-user_data_dir/-extension/-chrome_flag")
+		}
+		if len(ignored) > 0 {
+			GetGlobalLogger().Warn(fmt.Sprintf("Browser launch options %s are ignored with -remote_debugging_url, configure the remote browser instead", strings.Join(ignored, ", ")))
+		}
+		return chromedp.NewRemoteAllocator(context.Background(), conf.RemoteURL)
+	}
+
 	opts := append(chromedp.DefaultExecAllocatorOptions[:],
 		chromedp.Flag("headless", !conf.Headless),
 	)
@@ -235,6 +277,23 @@ func initBrowser(conf *BrowserConfig) (context.Context, context.CancelFunc) {
 	if conf.ChromiumPath != "" {
 		opts = append(opts, chromedp.ExecPath(conf.ChromiumPath))
 	}
+	// 证书校验和自定义 CA
+	if conf.TLS != nil {
+		opts = append(opts, conf.TLS.BrowserOptions()...)
+	}
+	// 上游代理
+	if conf.Proxy != nil {
+		opts = append(opts,
+			chromedp.ProxyServer(conf.Proxy.ServerFlag()),
+			chromedp.Flag("proxy-bypass-list", conf.Proxy.BypassFlag()),
+		)
+	}
+	// 主机解析覆盖
+	if len(conf.Resolve) > 0 {
+		opts = append(opts, chromedp.Flag("host-resolver-rules", hostResolverRulesFlag(conf.Resolve)))
+	}
+	// 配置文件、扩展和额外参数
+	opts = append(opts, profileOptions(conf)...)
 	allocCtx, cancel := chromedp.NewExecAllocator(context.Background(), opts...)
 
 	return allocCtx, cancel
@@ -246,6 +305,10 @@ type TabState struct {
 	currentReq  request
 	requestID   network.RequestID
 	topFrameID  cdp.FrameID
+	sessionLost bool                                    // 当前导航过程中检测到会话丢失
+	requestURLs map[network.RequestID]string            // 等待响应的请求 URL，用于关联 ResponseReceivedExtraInfo
+	extraInfos  map[network.RequestID][]network.Headers // 先于请求事件到达的 ResponseReceivedExtraInfo 响应头
+	webSockets  map[network.RequestID]*webSocketConn    // 打开的 WebSocket 连接，不随导航重置
 }
 
 // UpdateRequestState 更新当前请求状态
@@ -253,6 +316,55 @@ func (ts *TabState) UpdateRequestState(req request) {
 	ts.mu.Lock()
 	defer ts.mu.Unlock()
 	ts.currentReq = req
+	ts.sessionLost = false
+	ts.requestURLs = make(map[network.RequestID]string)
+	ts.extraInfos = make(map[network.RequestID][]network.Headers)
+}
+
+// RememberRequestURL 记录请求 URL，返回先于请求事件到达、等待关联的原始响应头
+// 须在事件监听器中同步调用，保证在同一请求的 ResponseReceivedExtraInfo 处理前完成
+func (ts *TabState) RememberRequestURL(id network.RequestID, rawURL string) []network.Headers {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+	if ts.requestURLs == nil {
+		ts.requestURLs = make(map[network.RequestID]string)
+	}
+	ts.requestURLs[id] = rawURL
+	pending := ts.extraInfos[id]
+	delete(ts.extraInfos, id)
+	return pending
+}
+
+// TakeRequestURL 获取并移除请求 URL，请求事件尚未到达时暂存响应头，由 RememberRequestURL 取回
+func (ts *TabState) TakeRequestURL(id network.RequestID, headers network.Headers) (string, bool) {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+	rawURL, ok := ts.requestURLs[id]
+	if !ok {
+		if ts.extraInfos == nil {
+			ts.extraInfos = make(map[network.RequestID][]network.Headers)
+		}
+		ts.extraInfos[id] = append(ts.extraInfos[id], headers)
+		return "", false
+	}
+	delete(ts.requestURLs, id)
+	return rawURL, true
+}
+
+// MarkSessionLost 标记会话丢失
+func (ts *TabState) MarkSessionLost() {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+	ts.sessionLost = true
+}
+
+// TakeSessionLost 获取并清除会话丢失标记
+func (ts *TabState) TakeSessionLost() bool {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+	lost := ts.sessionLost
+	ts.sessionLost = false
+	return lost
 }
 
 // GetCurrentReq 获取当前请求
@@ -277,21 +389,39 @@ func (ts *TabState) GetRequestID() (network.RequestID, cdp.FrameID) {
 	return ts.requestID, ts.topFrameID
 }
 
+// trackResponseHeaders 在事件处理池中追踪原始响应头中的 Set-Cookie 和令牌
+func trackResponseHeaders(pool *EventWorkerPool, wg *sync.WaitGroup, session *Session, rawURL string, headers network.Headers) {
+	wg.Add(1)
+	if !pool.Submit(func() {
+		defer wg.Done()
+		session.TrackResponse(rawURL, headers)
+	}) {
+		wg.Done()
+	}
+}
+
 // handleRequestWillBeSent 处理即将发送 HTTP 请求事件
-func handleRequestWillBeSent(ev *network.EventRequestWillBeSent, tabState *TabState, reqC chan request, store *RequestStore, state *CrawlerState) {
+func handleRequestWillBeSent(ev *network.EventRequestWillBeSent, tabState *TabState, reqC chan request, store *RequestStore, state *CrawlerState, conf *TabConfig) {
 	if ev.RequestID.String() == ev.LoaderID.String() && ev.Type.String() == "Document" {
 		// 顶层框架导航、点击链接（当前页面）和 location.href 赋值导航
 		tabState.UpdateRequestID(ev.RequestID, ev.FrameID)
+
+		// 会话检测：当前页面被重定向或跳转到登录页
+		_, topFrameID := tabState.GetRequestID()
+		if normalizedURL, err := normalizeURL(ev.Request.URL); err == nil && ev.FrameID == topFrameID &&
+			normalizedURL != tabState.GetCurrentReq().URL && conf.SessionMonitor.IsLoginURL(normalizedURL) {
+			tabState.MarkSessionLost()
+		}
 	}
 
 	// 获取后端重定向响应里可能的链接
 	if ev.RedirectHasExtraInfo {
-		req, _ := http.NewRequest(http.MethodGet, ev.RedirectResponse.URL, nil)
-		for name, value := range ev.Request.Headers {
-			req.Header.Set(name, value.(string))
+		req, err := newGoRequest(conf.HTTPClient, http.MethodGet, ev.RedirectResponse.URL, ev.Request.Headers)
+		if err != nil {
+			return
 		}
 
-		res, err := httpClient.Do(req)
+		res, err := conf.HTTPClient.Do(req)
 		if err != nil {
 			GetGlobalLogger().ErrorWithURL("Failed to fetch redirect response", ev.RedirectResponse.URL, err)
 			return
@@ -312,11 +442,11 @@ func handleRequestWillBeSent(ev *network.EventRequestWillBeSent, tabState *TabSt
 				relLink, _ := url.Parse(link)
 				absLink := base.ResolveReference(relLink)
 				newReq := geneRequest("GET", absLink.String(), ev.Request.Headers, "", "redirect")
-				if store.SaveRequest(newReq) {
+				if store.SaveRequestFrom(ev.RedirectResponse.URL, newReq) {
 					key := "GET" + newReq.URL
 					if !state.IsVisited(key) {
 						state.MarkVisited(key)
-						reqC <- newReq
+						state.Enqueue(reqC, newReq)
 					}
 				}
 			}
@@ -325,13 +455,19 @@ func handleRequestWillBeSent(ev *network.EventRequestWillBeSent, tabState *TabSt
 }
 
 // handleRequestPaused 处理请求拦截事件
-func handleRequestPaused(ev *fetch.EventRequestPaused, ctx context.Context, tabState *TabState, reqC chan request, store *RequestStore, state *CrawlerState) {
+func handleRequestPaused(ev *fetch.EventRequestPaused, ctx context.Context, tabState *TabState, reqC chan request, store *RequestStore, state *CrawlerState, conf *TabConfig) {
 	req := tabState.GetCurrentReq()
 	requestID, topFrameID := tabState.GetRequestID()
 	// 获取目标（标签页）执行上下文
 	c := chromedp.FromContext(ctx)
 	targetCtx := cdp.WithExecutor(ctx, c.Target)
 
+	// 响应阶段的拦截（改写规则需要删除响应头），请求已在请求阶段处理
+	if isResponseStage(ev) {
+		_ = continueResponse(targetCtx, ev)
+		return
+	}
+
 	// 获取请求数据
 	var postData string
 	if ev.Request.HasPostData && ev.NetworkID != "" {
@@ -346,45 +482,69 @@ func handleRequestPaused(ev *fetch.EventRequestPaused, ctx context.Context, tabS
 	resourceType := ev.ResourceType.String()
 	pausedRequestID := ev.RequestID
 
-	// 丢弃不影响 DOM 结构的静态资源下载请求，如：图片和字体等
+	// 匹配模拟响应规则的请求不会发出，跳过拦截策略、静态资源丢弃和登出过滤，由 continuePaused 返回模拟响应
+	mocked := rewriteConfig.hasMock(ev)
+
+	// 按拦截策略处理，当前标签页的顶层导航不受策略影响
+	action, matched := conf.Policy.Match(resourceType, pausedURL)
+	if matched && !mocked && !(ev.NetworkID == requestID && ev.FrameID == topFrameID) {
+		switch action {
+		case PolicyBlock:
+			_ = fetch.FailRequest(pausedRequestID, network.ErrorReasonBlockedByClient).Do(targetCtx)
+			return
+		case PolicyRecord:
+			source := "dom"
+			if resourceType == "XHR" || resourceType == "Fetch" {
+				source = strings.ToLower(resourceType)
+			}
+			newReq := geneRequest(method, pausedURL, headers, postData, source)
+			newReq.Blocked = true
+			store.SaveRequestFrom(req.URL, newReq)
+			_ = fetch.FailRequest(pausedRequestID, network.ErrorReasonBlockedByClient).Do(targetCtx)
+			return
+		}
+	}
+
+	// 丢弃不影响 DOM 结构的静态资源下载请求，如：图片和字体等，策略放行的除外
 	// 但记录动态加载的静态资源
-	if failResourceTypes[resourceType] {
+	if failResourceTypes[resourceType] && action != PolicyAllow && !mocked {
 		u, _ := url.Parse(pausedURL)
 		newReq := geneRequest(method, pausedURL, headers, postData, "dom")
+		newReq.Blocked = true
 		if u.RawQuery != "" {
-			store.SaveRequest(newReq)
+			store.SaveRequestFrom(req.URL, newReq)
 		}
 		_ = fetch.FailRequest(pausedRequestID, network.ErrorReasonAborted).Do(targetCtx)
 		return
 	}
 
 	// 丢弃登出请求
-	if strings.Contains(strings.ToLower(pausedURL), "logout") {
+	if strings.Contains(strings.ToLower(pausedURL), "logout") && !mocked {
 		_ = fetch.FailRequest(pausedRequestID, network.ErrorReasonAborted).Do(targetCtx)
 		return
 	}
 
 	// 放行样式表和脚本
 	if goResourceTypes[resourceType] {
-		_ = fetch.ContinueRequest(pausedRequestID).Do(targetCtx)
+		_ = continuePaused(targetCtx, ev, conf)
 		return
 	}
 
 	// 异步请求
 	if resourceType == "XHR" || resourceType == "Fetch" {
 		newReq := geneRequest(method, pausedURL, headers, postData, strings.ToLower(resourceType))
-		store.SaveRequest(newReq)
+		store.SaveRequestFrom(req.URL, newReq)
 		
 		// 继续请求并尝试获取响应体解析 JSON 中的 URL
-		_ = fetch.ContinueRequest(pausedRequestID).Do(targetCtx)
+		_ = continuePaused(targetCtx, ev, conf)
 		
 		// 在后台尝试解析响应（不阻塞）
-		go func() {
+		state.Go(func() {
 			time.Sleep(100 * time.Millisecond) // 等待响应
 			if body, err := fetch.GetResponseBody(pausedRequestID).Do(targetCtx); err == nil {
-				extractUrlsFromJSON(string(body), req.Headers, store, state, reqC)
+				extractUrlsFromJSON(pausedURL, string(body), req.Headers, store, state, reqC)
 			}
-		}()
+		})
 		return
 	}
 
@@ -422,18 +582,18 @@ func handleRequestPaused(ev *fetch.EventRequestPaused, ctx context.Context, tabS
 		if pausedURL == req.URL && method == "GET" {
 			// 顶层框架导航
 			// 放行
-			fetch.ContinueRequest(pausedRequestID).Do(targetCtx)
+			_ = continuePaused(targetCtx, ev, conf)
 		} else {
 			// JS 点击链接(标签 a 未设置 target="_blank" 属性)、location.href 赋值导航和提交表单到当前页
 			// 阻断
 			_ = fetch.FailRequest(pausedRequestID, network.ErrorReasonAborted).Do(targetCtx)
 			newReq := geneRequest(method, pausedURL, headers, postData, "navigation")
-			if store.SaveRequest(newReq) {
+			if store.SaveRequestFrom(req.URL, newReq) {
 				if method == "GET" {
 					key := "GET" + newReq.URL
 					if !state.IsVisited(key) {
 						state.MarkVisited(key)
-						reqC <- newReq
+						state.Enqueue(reqC, newReq)
 					}
 				}
 			}
@@ -441,8 +601,15 @@ func handleRequestPaused(ev *fetch.EventRequestPaused, ctx context.Context, tabS
 		return
 	}
 
+	// Server-Sent Events 流，记录端点后放行
+	if resourceType == "EventSource" {
+		store.SaveRequestFrom(req.URL, geneRequest(method, pausedURL, headers, postData, "eventsource"))
+		_ = continuePaused(targetCtx, ev, conf)
+		return
+	}
+
 	// 放行其它资源类型（如：WebSocket）请求
-	_ = fetch.ContinueRequest(pausedRequestID).Do(targetCtx)
+	_ = continuePaused(targetCtx, ev, conf)
 }
 
 // handleTargetCreated 处理新标签页创建事件
@@ -451,6 +618,10 @@ func handleTargetCreated(ev *target.EventTargetCreated, ctx context.Context) {
 	c := chromedp.FromContext(ctx)
 	browserCtx := cdp.WithExecutor(ctx, c.Browser)
 
+	if !isCrawlTarget(ev.TargetInfo) {
+		// 扩展的 service worker 和页面等不是爬取目标
+		return
+	}
 	if ev.TargetInfo.OpenerID == c.Target.TargetID {
 		// 如果新标签页由当前标签页打开，则关闭新标签页
 		// 阻止跳转到新标签页的行为
@@ -509,6 +680,8 @@ func handleLoadEventFired(ctx context.Context, conf *TabConfig) {
 	runtime.Evaluate(mutationObserverJS).Do(targetCtx)
 	// 收集初始 DOM 中的链接
 	runtime.Evaluate(collectLinksJS).Do(targetCtx)
+	// 收集 meta 和表单中的反 CSRF 令牌（在提交表单前）
+	runtime.Evaluate(collectTokensJS).Do(targetCtx)
 	// 自动填充和提交表单
 	runtime.Evaluate(fillAndSubmitFormsJS).Do(targetCtx)
 	// 触发事件和执行 JS 伪协议
@@ -528,23 +701,41 @@ func handleJavascriptDialog(ctx context.Context) {
 }
 
 // handleBindingCalled 处理绑定函数调用事件
-func handleBindingCalled(ev *runtime.EventBindingCalled, tabState *TabState, reqC chan request, store *RequestStore, state *CrawlerState) {
+func handleBindingCalled(ev *runtime.EventBindingCalled, tabState *TabState, reqC chan request, store *RequestStore, state *CrawlerState, conf *TabConfig) {
 	var payload bindingPayload
 	_ = json.Unmarshal([]byte(ev.Payload), &payload)
 
+	// 页面中的令牌只记录来源，不产生新请求
+	if payload.Source == "csrf-token" {
+		conf.Session.RecordTokenFields(payload.URL, payload.Tokens)
+		return
+	}
+
+	// postMessage 监听器和消息结构只记录，不产生新请求
+	if payload.Source == "postmessage" {
+		store.RecordPostMessage(payload.URL, payload.Message)
+		return
+	}
+
+	// 表单隐藏字段只做标记，不产生新请求
+	if payload.Source == "hidden-input" {
+		store.MarkHiddenParams(payload.URL, payload.Params)
+		return
+	}
+
 	req := tabState.GetCurrentReq()
 	newReq := geneRequest("GET", payload.URL, req.Headers, "", payload.Source)
-	if store.SaveRequest(newReq) {
+	if store.SaveRequestFrom(req.URL, newReq) {
 		key := "GET" + newReq.URL
 		if !state.IsVisited(key) {
 			state.MarkVisited(key)
-			reqC <- newReq
+			state.Enqueue(reqC, newReq)
 		}
 	}
 }
 
 // extractUrlsFromJSON 从 JSON 响应中提取 URL
-func extractUrlsFromJSON(body string, headers map[string]interface{}, store *RequestStore, state *CrawlerState, reqC chan request) {
+func extractUrlsFromJSON(parent string, body string, headers map[string]interface{}, store *RequestStore, state *CrawlerState, reqC chan request) {
 	// 简单的 URL 提取：查找所有看起来像 URL 的字符串
 	// 匹配 "url": "xxx", "link": "xxx", "href": "xxx" 等
 	var data interface{}
@@ -556,7 +747,7 @@ func extractUrlsFromJSON(body string, headers map[string]interface{}, store *Req
 	
 	for _, u := range urls {
 		newReq := geneRequest("GET", u, headers, "", "json")
-		if store.SaveRequest(newReq) {
+		if store.SaveRequestFrom(parent, newReq) {
 			key := "GET" + newReq.URL
 			if !state.IsVisited(key) {
 				state.MarkVisited(key)
@@ -647,7 +838,9 @@ func (trc *TabRecoveryConfig) GetCooldown() time.Duration {
 }
 
 // runTabWithRecovery 带崩溃恢复的标签页运行
-func runTabWithRecovery(num int, reqC chan request, store *RequestStore, tctx context.Context, conf *TabConfig, state *CrawlerState, progressStats *ProgressStats, recoveryConfig *TabRecoveryConfig) {
+// tabs 在标签页退出（不再重启）时计数减一
+func runTabWithRecovery(num int, reqC chan request, store *RequestStore, tctx context.Context, conf *TabConfig, state *CrawlerState, progressStats *ProgressStats, recoveryConfig *TabRecoveryConfig, tabs *sync.WaitGroup) {
+	defer tabs.Done()
 	defer func() {
 		if r := recover(); r != nil {
 			if recoveryConfig.CanRestart() {
@@ -658,7 +851,8 @@ func runTabWithRecovery(num int, reqC chan request, store *RequestStore, tctx co
 				// 冷却后重新创建标签页
 				select {
 				case <-time.After(cooldown):
-					go runTabWithRecovery(num, reqC, store, tctx, conf, state, progressStats, recoveryConfig)
+					tabs.Add(1)
+					go runTabWithRecovery(num, reqC, store, tctx, conf, state, progressStats, recoveryConfig, tabs)
 				case <-tctx.Done():
 					GetGlobalLogger().Info(fmt.Sprintf("Tab %d context canceled during cooldown, not restarting", num))
 					return
@@ -674,7 +868,11 @@ func runTabWithRecovery(num int, reqC chan request, store *RequestStore, tctx co
 func runTab(num int, reqC chan request, store *RequestStore, tctx context.Context, conf *TabConfig, state *CrawlerState, progressStats *ProgressStats) {
 	var ctx context.Context = tctx
 	var cancel context.CancelFunc
-	if num > 1 {
+	if conf.Isolation == IsolationTab {
+		// 每个标签页使用独立的隐身浏览器上下文，cookie、存储和 service worker 互不影响
+		ctx, cancel = chromedp.NewContext(tctx, chromedp.WithNewBrowserContext())
+		defer cancel()
+	} else if num > 1 {
 		// 非第一个标签页通过继承第一个标签页创建
 		ctx, cancel = chromedp.NewContext(tctx)
 		defer cancel()
@@ -695,32 +893,99 @@ func runTab(num int, reqC chan request, store *RequestStore, tctx context.Contex
 	chromedp.ListenTarget(ctx, func(ev interface{}) {
 		switch ev := ev.(type) {
 		case *network.EventRequestWillBeSent:
+			// 记录请求 URL，响应的 Set-Cookie 在 ResponseReceivedExtraInfo 中按请求 ID 关联
+			// 先到达的原始响应头属于本请求，重定向时属于重定向响应
+			responseURL := ev.Request.URL
+			if ev.RedirectResponse != nil {
+				responseURL = ev.RedirectResponse.URL
+			}
+			for _, headers := range tabState.RememberRequestURL(ev.RequestID, ev.Request.URL) {
+				trackResponseHeaders(pool, &wg, conf.Session, responseURL, headers)
+			}
 			// 即将发送 HTTP 请求
 			wg.Add(1)
 			if !pool.Submit(func() {
 				defer wg.Done()
-				handleRequestWillBeSent(ev, tabState, reqC, store, state)
+				handleRequestWillBeSent(ev, tabState, reqC, store, state, conf)
+			}) {
+				wg.Done()
+			}
+		case *network.EventResponseReceived:
+			// 响应记录（写入持久化后端）
+			wg.Add(1)
+			if !pool.Submit(func() {
+				defer wg.Done()
+				store.SaveResponse(responseRecord{
+					URL:          ev.Response.URL,
+					ResourceType: ev.Type.String(),
+					Status:       ev.Response.Status,
+					MimeType:     ev.Response.MimeType,
+					Headers:      ev.Response.Headers,
+				})
 			}) {
 				wg.Done()
 			}
+		case *network.EventResponseReceivedExtraInfo:
+			// 原始响应头：追踪 Set-Cookie 和令牌响应头
+			if rawURL, ok := tabState.TakeRequestURL(ev.RequestID, ev.Headers); ok {
+				trackResponseHeaders(pool, &wg, conf.Session, rawURL, ev.Headers)
+			}
+		case *network.EventWebSocketCreated, *network.EventWebSocketWillSendHandshakeRequest,
+			*network.EventWebSocketHandshakeResponseReceived, *network.EventWebSocketFrameSent,
+			*network.EventWebSocketFrameReceived, *network.EventWebSocketClosed:
+			// WebSocket 端点和消息，同步处理以保持事件顺序
+			handleWebSocketEvent(ev, tabState, store)
 		case *fetch.EventRequestPaused:
 			// 拦截请求
 			wg.Add(1)
 			if !pool.Submit(func() {
 				defer wg.Done()
-				handleRequestPaused(ev, ctx, tabState, reqC, store, state)
+				handleRequestPaused(ev, ctx, tabState, reqC, store, state, conf)
+			}) {
+				wg.Done()
+			}
+		case *fetch.EventAuthRequired:
+			// HTTP 认证质询
+			wg.Add(1)
+			if !pool.Submit(func() {
+				defer wg.Done()
+				c := chromedp.FromContext(ctx)
+				if err := continueWithAuth(cdp.WithExecutor(ctx, c.Target), ev, conf.Session); err != nil {
+					GetGlobalLogger().ErrorWithURL("Failed to answer auth challenge", ev.Request.URL, err)
+				}
 			}) {
 				wg.Done()
 			}
 		case *target.EventTargetCreated:
-			// 新标签页创建事件，并实时关闭
+			// 新标签页创建事件，并实时关闭；共享 Worker 和 Service Worker 对所有标签页可见，由首个发现的标签页附加
 			wg.Add(1)
 			if !pool.Submit(func() {
 				defer wg.Done()
+				if isWorkerTarget(ev.TargetInfo) && ev.TargetInfo.Type != "worker" {
+					attachWorker(ctx, ev.TargetInfo, tabState, state.workers, store, conf)
+					return
+				}
 				handleTargetCreated(ev, ctx)
 			}) {
 				wg.Done()
 			}
+		case *target.EventAttachedToTarget:
+			// 当前标签页创建的专用 Worker 自动附加
+			if isWorkerTarget(ev.TargetInfo) {
+				state.workers.addSession(ev.SessionID, ev.TargetInfo.TargetID)
+				wg.Add(1)
+				if !pool.Submit(func() {
+					defer wg.Done()
+					attachWorker(ctx, ev.TargetInfo, tabState, state.workers, store, conf)
+				}) {
+					wg.Done()
+				}
+			}
+		case *target.EventTargetDestroyed:
+			// Worker 结束后释放其上下文
+			state.workers.detach(ev.TargetID)
+		case *target.EventDetachedFromTarget:
+			state.workers.detachSession(ev.SessionID)
 		case *page.EventLoadEventFired:
 			// 页面加载完成
 			// chromedp.Navigate 会等待该事件
@@ -745,7 +1010,7 @@ func runTab(num int, reqC chan request, store *RequestStore, tctx context.Contex
 			wg.Add(1)
 			if !pool.Submit(func() {
 				defer wg.Done()
-				handleBindingCalled(ev, tabState, reqC, store, state)
+				handleBindingCalled(ev, tabState, reqC, store, state, conf)
 			}) {
 				wg.Done()
 			}
@@ -754,18 +1019,19 @@ func runTab(num int, reqC chan request, store *RequestStore, tctx context.Contex
 
 	// Tab 初始化（每个 tab 一次）
 	if err := chromedp.Run(ctx,
-		// 开启请求拦截
-		fetch.Enable(),
+		// 开启请求拦截，配置了凭据或代理认证时同时处理认证质询
+		fetch.Enable().WithHandleAuthRequests(handleAuthRequests(conf.Session)),
 		// 在 window 对象中增加绑定
 		// 通过该绑定实现 js 到 go 的通信，并通过 hook bindingCalled 事件接收信息
 		runtime.AddBinding(bindingName),
+		// 设备仿真：视口、触屏、User-Agent、语言、时区和地理位置
+		conf.Emulation.Apply(),
+		// 隐身层：User-Agent、Client Hints 和指纹修正脚本
+		conf.Stealth.Apply(conf.Emulation),
 		chromedp.ActionFunc(func(ctx context.Context) error {
-			// 加载 bypass 脚本
-			_, err := page.AddScriptToEvaluateOnNewDocument(bypassHeadlessDetectJS).Do(ctx)
-			if err != nil {
-				return err
-			}
-			return nil
+			// 加载 postMessage hook 脚本，须在页面注册监听器之前执行
+			_, err := page.AddScriptToEvaluateOnNewDocument(postMessageHookJS).Do(ctx)
+			return err
 		}),
 		chromedp.ActionFunc(func(ctx context.Context) error {
 			// 加载初始化 hook 脚本
@@ -775,6 +1041,23 @@ func runTab(num int, reqC chan request, store *RequestStore, tctx context.Contex
 			}
 			return nil
 		}),
+		chromedp.ActionFunc(func(ctx context.Context) error {
+			// 写入会话 cookie 和 Web Storage（sessionStorage 按标签页隔离，需逐个写入）
+			if !conf.SeedSession {
+				return nil
+			}
+			if cookies := conf.Session.CookieParams(); len(cookies) > 0 {
+				if err := network.SetCookies(cookies).Do(ctx); err != nil {
+					return err
+				}
+			}
+			if script := conf.Session.StorageScript(); script != "" {
+				if _, err := page.AddScriptToEvaluateOnNewDocument(script).Do(ctx); err != nil {
+					return err
+				}
+			}
+			return nil
+		}),
 	); err != nil {
 		GetGlobalLogger().Error(fmt.Sprintf("Tab %d init error", num), err)
 		return
@@ -799,57 +1082,74 @@ func runTab(num int, reqC chan request, store *RequestStore, tctx context.Contex
 			
 			// 更新当前请求状态
 			tabState.UpdateRequestState(req)
+			state.workers.setActive(tabState)
 			
-			// 运行标签页，执行爬虫任务（带重试）
-			err := retryWithBackoff(func() error {
-				return chromedp.Run(ctx,
-					network.SetExtraHTTPHeaders(req.Headers),
-					chromedp.Navigate(req.URL),
-				)
-			}, 2, 500*time.Millisecond, req.URL)
-			
-			if err != nil && !strings.Contains(err.Error(), "net::ERR_ABORTED") {
-				GetGlobalLogger().ErrorWithURL("Error crawling URL", req.URL, err)
-				if progressStats != nil {
-					progressStats.UpdateTabState(num, "waiting", "", "")
-					progressStats.IncrementError()
+			// 重新登录期间暂停导航，导航及会话检测期间持有读锁
+			navStart := time.Now()
+			sessionLost, canceled := false, false
+			conf.SessionMonitor.Guard(func() {
+				// 运行标签页，执行爬虫任务（带重试）
+				err := retryWithBackoff(func() error {
+					return chromedp.Run(ctx,
+						network.SetExtraHTTPHeaders(conf.browserHeaders(req.Headers)),
+						chromedp.Navigate(req.URL),
+					)
+				}, 2, 500*time.Millisecond, req.URL)
+				
+				if err != nil && !strings.Contains(err.Error(), "net::ERR_ABORTED") {
+					GetGlobalLogger().ErrorWithURL("Error crawling URL", req.URL, err)
+					if progressStats != nil {
+						progressStats.UpdateTabState(num, "waiting", "", "")
+						progressStats.IncrementError()
+					}
+					// 不要 Fatal，继续处理下一个请求
+					return
 				}
-				// 不要 Fatal，继续处理下一个请求
-				continue
-			}
 
-			// 等待 goroutine 执行完成（带上下文和超时保护）
-			go func() {
-				wg.Wait()
-				close(wgDone)
-			}()
+				// 等待 goroutine 执行完成（带上下文和超时保护）
+				go func() {
+					wg.Wait()
+					close(wgDone)
+				}()
 
-			select {
-			case <-wgDone:
-				// 正常完成
-				// 更新标签页状态为等待
-				if progressStats != nil {
-					progressStats.UpdateTabState(num, "waiting", "", "")
-					progressStats.IncrementProcessed()
-				}
-				// 重新创建 wgDone channel 用于下一次请求
-				wgDone = make(chan struct{})
-				
-			case <-time.After(conf.TabTimeout):
-				// 超时
-				GetGlobalLogger().WarnWithURL("Tab timeout", req.URL)
-				if progressStats != nil {
-					progressStats.UpdateTabState(num, "waiting", "", "")
-					progressStats.IncrementError()
+				select {
+				case <-wgDone:
+					// 正常完成
+					// 更新标签页状态为等待
+					if progressStats != nil {
+						progressStats.UpdateTabState(num, "waiting", "", "")
+						progressStats.IncrementProcessed()
+					}
+					// 重新创建 wgDone channel 用于下一次请求
+					wgDone = make(chan struct{})
+					
+				case <-time.After(conf.TabTimeout):
+					// 超时
+					GetGlobalLogger().WarnWithURL("Tab timeout", req.URL)
+					if progressStats != nil {
+						progressStats.UpdateTabState(num, "waiting", "", "")
+						progressStats.IncrementError()
+					}
+					// 重新创建 wgDone channel 用于下一次请求
+					wgDone = make(chan struct{})
+					
+				case <-ctx.Done():
+					// 上下文取消
+					canceled = true
+					return
 				}
-				// 重新创建 wgDone channel 用于下一次请求
-				wgDone = make(chan struct{})
-				
-			case <-ctx.Done():
-				// 上下文取消
+
+				// 检测会话是否丢失
+				sessionLost = conf.SessionMonitor.Check(ctx, tabState, req)
+			})
+			if canceled {
 				GetGlobalLogger().Info(fmt.Sprintf("Tab %d context canceled", num))
 				return
 			}
+			// 会话丢失则重新登录，并将登出状态下访问的请求重新入队
+			if sessionLost {
+				handleSessionLost(ctx, num, req, conf, reqC, navStart)
+			}
 			
 		case <-ctx.Done():
 			// 上下文取消，退出
@@ -864,10 +1164,15 @@ func crawl(store *RequestStore, allocCtx context.Context, conf *TabConfig, progr
 	crawlCtx, crawlCancel := context.WithTimeout(allocCtx, conf.CrawlTotalTime)
 	defer crawlCancel()
 	
-	// 创建第一个标签页
+	// 创建第一个标签页，多角色爬取时每个角色使用独立的浏览器上下文，cookie 和存储互不影响
+	var ctxOpts []chromedp.ContextOption
+	if conf.Role != "" || conf.Isolation == IsolationRole {
+		ctxOpts = append(ctxOpts, chromedp.WithNewBrowserContext())
+	}
 	ctx, cancel := chromedp.NewContext(
 		crawlCtx,
 		//chromedp.WithDebugf(log.Printf),
+		ctxOpts...,
 	)
 	defer cancel()
 
@@ -877,20 +1182,73 @@ func crawl(store *RequestStore, allocCtx context.Context, conf *TabConfig, progr
 		log.Fatalln(err)
 	}
 
+	// 内置仿真配置使用真实浏览器的 Chrome 版本，conf 为每个仿真配置的副本
+	if profile, err := conf.Emulation.withBrowserVersion(ctx); err != nil {
+		GetGlobalLogger().Warn(fmt.Sprintf("Failed to get browser version for emulation profile: %v", err))
+	} else if profile != conf.Emulation {
+		conf.Emulation = profile
+		conf.Headers["User-Agent"] = profile.UserAgent
+	}
+
+	// 在独立标签页中执行登录脚本，会话由所有标签页共享
+	if conf.LoginScript != nil {
+		progressStats.UpdateField("phase", "Logging in")
+		if err := runLogin(ctx, conf); err != nil {
+			GetGlobalLogger().Error("Login failed, crawling without session", err)
+		}
+		progressStats.UpdateField("phase", "Crawling")
+	}
+
 	// 根据并发数动态调整 channel 缓冲区大小
 	bufferSize := conf.TabConcurrentQuantity * 50
 	reqC := make(chan request, bufferSize)
 
-	// 创建爬虫状态管理器
-	state := NewCrawlerState()
+	// 创建爬虫状态管理器，共享 Worker 和 Service Worker 的附加状态和生命周期限定在本次爬取内
+	state := NewCrawlerState(crawlCtx.Done())
+	state.workers = newWorkerRegistry(ctx)
+	defer state.workers.close()
 
 	// 创建多个标签页，并发执行爬虫任务（带崩溃恢复）
+	var tabs sync.WaitGroup
 	for i := 1; i <= conf.TabConcurrentQuantity; i++ {
 		recoveryConfig := NewTabRecoveryConfig(3) // 最多重启3次
-		go runTabWithRecovery(i, reqC, store, ctx, conf, state, progressStats, recoveryConfig)
+		tabs.Add(1)
+		go runTabWithRecovery(i, reqC, store, ctx, conf, state, progressStats, recoveryConfig, &tabs)
 	}
 
-	reqC <- store.GetRequests()[0]
+	// 返回前取消标签页，等待标签页、事件处理和后台任务退出，下一个角色或仿真配置的爬取才能开始
+	// reqC 不关闭：事件处理可能仍在入队，爬取结束后入队不再阻塞
+	defer func() {
+		crawlCancel()
+		tabs.Wait()
+		state.Wait()
+	}()
+
+	// 初始 GET 请求（入口、种子、导入的请求，以及之前的角色或仿真配置发现的请求）
+	// 数量可能远超队列容量，先放入待入队列表，队列有空位时再入队，为标签页中发现的新请求保留一半容量
+	frontier := make([]request, 0)
+	for _, req := range store.GetRequests() {
+		key := "GET" + req.URL
+		if req.Method != "GET" || state.IsVisited(key) {
+			continue
+		}
+		state.MarkVisited(key)
+		frontier = append(frontier, req)
+	}
+	feedFrontier := func() bool {
+		fed := false
+		for len(frontier) > 0 && len(reqC) < cap(reqC)/2 {
+			select {
+			case reqC <- frontier[0]:
+				frontier = frontier[1:]
+				fed = true
+			default:
+				return fed
+			}
+		}
+		return fed
+	}
+	feedFrontier()
 
 	// 爬取调度：支持提前收敛
 	idleWindow := conf.WaitJSExecTime // 使用 WaitJSExecTime 作为空闲窗口
@@ -899,8 +1257,6 @@ func crawl(store *RequestStore, allocCtx context.Context, conf *TabConfig, progr
 	
 	ticker := time.NewTicker(1 * time.Second)
 	defer ticker.Stop()
-	
-	defer close(reqC) // 确保在函数退出时关闭通道
 
 	for {
 		select {
@@ -908,15 +1264,18 @@ func crawl(store *RequestStore, allocCtx context.Context, conf *TabConfig, progr
 			// 更新进度统计
 			currentRequestCount := store.GetRequestCount()
 			progressStats.UpdateField("total", currentRequestCount)
-			progressStats.UpdateField("queued", len(reqC))
-			progressStats.UpdateField("processed", currentRequestCount-len(reqC))
+			progressStats.UpdateField("queued", len(reqC)+len(frontier))
+			progressStats.UpdateField("processed", currentRequestCount-len(reqC)-len(frontier))
 			
 			// 检查是否可以提前收敛
+			if feedFrontier() {
+				lastActivityTime = time.Now()
+			}
 			if currentRequestCount > lastRequestCount {
 				// 有新请求，更新活动时间
 				lastActivityTime = time.Now()
 				lastRequestCount = currentRequestCount
-			} else if len(reqC) == 0 && time.Since(lastActivityTime) >= idleWindow {
+			} else if len(reqC) == 0 && len(frontier) == 0 && time.Since(lastActivityTime) >= idleWindow {
 				// 队列为空且已空闲足够长时间，提前结束
 				GetGlobalLogger().Info("Crawl completed: queue idle")
 				return
@@ -0,0 +1,292 @@
+package main
+
+import (
+	b64 "encoding/base64"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+)
+
+// diffEndpoint 端点：请求方法 + 归一化 URL 模式
+type diffEndpoint struct {
+	Method  string   `json:"method"`
+	Pattern string   `json:"pattern"`
+	Params  []string `json:"params,omitempty"`
+}
+
+// paramChange 端点参数变化
+type paramChange struct {
+	Method  string   `json:"method"`
+	Pattern string   `json:"pattern"`
+	Params  []string `json:"params"`
+}
+
+// methodChange 端点请求方法变化
+type methodChange struct {
+	Pattern    string   `json:"pattern"`
+	OldMethods []string `json:"old_methods"`
+	NewMethods []string `json:"new_methods"`
+}
+
+// diffResult 两次爬取结果的差异
+type diffResult struct {
+	Added          []diffEndpoint `json:"added"`
+	Removed        []diffEndpoint `json:"removed"`
+	NewParams      []paramChange  `json:"new_params"`
+	RemovedParams  []paramChange  `json:"removed_params"`
+	ChangedMethods []methodChange `json:"changed_methods"`
+}
+
+// loadRequests 读取 requests.json
+func loadRequests(path string) ([]request, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var requests []request
+	if err := json.Unmarshal(data, &requests); err != nil {
+		return nil, fmt.Errorf("parse %s: %w", path, err)
+	}
+	return requests, nil
+}
+
+// requestParamNames 获取请求的查询参数和请求体参数名
+func requestParamNames(req request) []string {
+	names := make(map[string]bool)
+	if u, err := url.Parse(req.URL); err == nil {
+		for name := range u.Query() {
+			names["query:"+name] = true
+		}
+	}
+
+	// 旧版本结果没有解析后的请求体，按原始数据重新解析
+	body := req.Body
+	if body == nil && req.Data != "" {
+		if data, err := b64.StdEncoding.DecodeString(req.Data); err == nil {
+			body = decodeBody(getHeader(req.Headers, "Content-Type"), data)
+		}
+	}
+	if body != nil {
+		for _, param := range body.Params {
+			names[body.Kind+":"+param.Name] = true
+		}
+	}
+	return sortedKeys(names)
+}
+
+// sortedKeys 返回排序后的 map 键
+func sortedKeys(m map[string]bool) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// indexEndpoints 按端点聚合请求参数
+func indexEndpoints(requests []request) map[string]map[string]bool {
+	endpoints := make(map[string]map[string]bool)
+	for _, req := range requests {
+		key := req.Method + " " + urlPattern(req.URL)
+		if endpoints[key] == nil {
+			endpoints[key] = make(map[string]bool)
+		}
+		for _, name := range requestParamNames(req) {
+			endpoints[key][name] = true
+		}
+	}
+	return endpoints
+}
+
+// splitEndpointKey 拆分端点键
+func splitEndpointKey(key string) (string, string) {
+	method, pattern, _ := strings.Cut(key, " ")
+	return method, pattern
+}
+
+// diffRequests 比较两次爬取结果
+func diffRequests(oldRequests, newRequests []request) *diffResult {
+	oldEndpoints := indexEndpoints(oldRequests)
+	newEndpoints := indexEndpoints(newRequests)
+	result := &diffResult{
+		Added:          []diffEndpoint{},
+		Removed:        []diffEndpoint{},
+		NewParams:      []paramChange{},
+		RemovedParams:  []paramChange{},
+		ChangedMethods: []methodChange{},
+	}
+
+	oldMethods := make(map[string]map[string]bool)
+	newMethods := make(map[string]map[string]bool)
+	collect := func(endpoints map[string]map[string]bool, methods map[string]map[string]bool) {
+		for key := range endpoints {
+			method, pattern := splitEndpointKey(key)
+			if methods[pattern] == nil {
+				methods[pattern] = make(map[string]bool)
+			}
+			methods[pattern][method] = true
+		}
+	}
+	collect(oldEndpoints, oldMethods)
+	collect(newEndpoints, newMethods)
+
+	for _, key := range sortedKeys(toSet(newEndpoints)) {
+		method, pattern := splitEndpointKey(key)
+		oldParams, ok := oldEndpoints[key]
+		if !ok {
+			result.Added = append(result.Added, diffEndpoint{Method: method, Pattern: pattern, Params: sortedKeys(newEndpoints[key])})
+			continue
+		}
+		var added []string
+		for _, name := range sortedKeys(newEndpoints[key]) {
+			if !oldParams[name] {
+				added = append(added, name)
+			}
+		}
+		if len(added) > 0 {
+			result.NewParams = append(result.NewParams, paramChange{Method: method, Pattern: pattern, Params: added})
+		}
+	}
+
+	for _, key := range sortedKeys(toSet(oldEndpoints)) {
+		method, pattern := splitEndpointKey(key)
+		newParams, ok := newEndpoints[key]
+		if !ok {
+			result.Removed = append(result.Removed, diffEndpoint{Method: method, Pattern: pattern, Params: sortedKeys(oldEndpoints[key])})
+			continue
+		}
+		var removed []string
+		for _, name := range sortedKeys(oldEndpoints[key]) {
+			if !newParams[name] {
+				removed = append(removed, name)
+			}
+		}
+		if len(removed) > 0 {
+			result.RemovedParams = append(result.RemovedParams, paramChange{Method: method, Pattern: pattern, Params: removed})
+		}
+	}
+
+	// 同一 URL 模式在两次结果中都存在但方法集合不同
+	for _, pattern := range sortedKeys(toSet(newMethods)) {
+		oldSet, ok := oldMethods[pattern]
+		if !ok {
+			continue
+		}
+		oldList, newList := sortedKeys(oldSet), sortedKeys(newMethods[pattern])
+		if strings.Join(oldList, ",") != strings.Join(newList, ",") {
+			result.ChangedMethods = append(result.ChangedMethods, methodChange{Pattern: pattern, OldMethods: oldList, NewMethods: newList})
+		}
+	}
+
+	return result
+}
+
+// toSet 将 map 的键转为集合
+func toSet[V any](m map[string]V) map[string]bool {
+	set := make(map[string]bool, len(m))
+	for key := range m {
+		set[key] = true
+	}
+	return set
+}
+
+// printDiffText 输出文本格式的差异
+func printDiffText(result *diffResult) {
+	fmt.Printf("%s[+] Added endpoints (%d)%s\n", colorGreen, len(result.Added), colorReset)
+	for _, e := range result.Added {
+		fmt.Printf("    %-7s %s%s\n", e.Method, e.Pattern, formatParamList(e.Params))
+	}
+	fmt.Printf("%s[-] Removed endpoints (%d)%s\n", colorRed, len(result.Removed), colorReset)
+	for _, e := range result.Removed {
+		fmt.Printf("    %-7s %s%s\n", e.Method, e.Pattern, formatParamList(e.Params))
+	}
+	fmt.Printf("%s[+] New parameters (%d)%s\n", colorCyan, len(result.NewParams), colorReset)
+	for _, c := range result.NewParams {
+		fmt.Printf("    %-7s %s%s\n", c.Method, c.Pattern, formatParamList(c.Params))
+	}
+	fmt.Printf("%s[-] Removed parameters (%d)%s\n", colorYellow, len(result.RemovedParams), colorReset)
+	for _, c := range result.RemovedParams {
+		fmt.Printf("    %-7s %s%s\n", c.Method, c.Pattern, formatParamList(c.Params))
+	}
+	fmt.Printf("%s[*] Changed methods (%d)%s\n", colorBold, len(result.ChangedMethods), colorReset)
+	for _, c := range result.ChangedMethods {
+		fmt.Printf("    %s %s -> %s\n", c.Pattern, strings.Join(c.OldMethods, ","), strings.Join(c.NewMethods, ","))
+	}
+}
+
+// formatParamList 格式化参数列表
+func formatParamList(params []string) string {
+	if len(params) == 0 {
+		return ""
+	}
+	return " [" + strings.Join(params, ", ") + "]"
+}
+
+// diffOptions diff 子命令参数
+type diffOptions struct {
+	Format  string
+	OldPath string
+	NewPath string
+}
+
+// parseDiffArgs 解析 diff 子命令参数，参数错误时向 output 输出用法
+func parseDiffArgs(args []string, output io.Writer) (*diffOptions, error) {
+	fs := flag.NewFlagSet("diff", flag.ContinueOnError)
+	fs.SetOutput(output)
+	format := fs.String("format", "text", "Output format: text, json")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: flamingo diff [-format text|json] old.json new.json")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+	if *format != "text" && *format != "json" {
+		err := fmt.Errorf("invalid -format %q, expected text or json", *format)
+		fmt.Fprintln(fs.Output(), err)
+		fs.Usage()
+		return nil, err
+	}
+	if fs.NArg() != 2 {
+		fs.Usage()
+		return nil, errors.New("expected old.json and new.json")
+	}
+	return &diffOptions{Format: *format, OldPath: fs.Arg(0), NewPath: fs.Arg(1)}, nil
+}
+
+// runDiff 执行 diff 子命令
+func runDiff(args []string) {
+	opts, err := parseDiffArgs(args, os.Stderr)
+	if errors.Is(err, flag.ErrHelp) {
+		os.Exit(0)
+	}
+	if err != nil {
+		os.Exit(2)
+	}
+
+	oldRequests, err := loadRequests(opts.OldPath)
+	if err != nil {
+		log.Fatalln(err)
+	}
+	newRequests, err := loadRequests(opts.NewPath)
+	if err != nil {
+		log.Fatalln(err)
+	}
+
+	result := diffRequests(oldRequests, newRequests)
+	if opts.Format == "json" {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		_ = encoder.Encode(result)
+		return
+	}
+	printDiffText(result)
+}
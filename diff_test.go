@@ -0,0 +1,83 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestDiffRequests(t *testing.T) {
+	oldRequests := []request{
+		{Method: "GET", URL: "https://a.com/users/1?sort=asc"},
+		{Method: "GET", URL: "https://a.com/old"},
+		{Method: "POST", URL: "https://a.com/login", Body: &RequestBody{Kind: BodyForm, Params: []BodyParam{{Name: "user"}, {Name: "remember"}}}},
+		{Method: "GET", URL: "https://a.com/items"},
+	}
+	newRequests := []request{
+		{Method: "GET", URL: "https://a.com/users/2?sort=desc&page=2"},
+		{Method: "GET", URL: "https://a.com/new"},
+		{Method: "POST", URL: "https://a.com/login", Body: &RequestBody{Kind: BodyForm, Params: []BodyParam{{Name: "user"}}}},
+		{Method: "GET", URL: "https://a.com/items"},
+		{Method: "DELETE", URL: "https://a.com/items"},
+	}
+	result := diffRequests(oldRequests, newRequests)
+
+	wantAdded := []diffEndpoint{
+		{Method: "DELETE", Pattern: "https://a.com/items", Params: []string{}},
+		{Method: "GET", Pattern: "https://a.com/new", Params: []string{}},
+	}
+	wantRemoved := []diffEndpoint{{Method: "GET", Pattern: "https://a.com/old", Params: []string{}}}
+	wantNewParams := []paramChange{{Method: "GET", Pattern: "https://a.com/users/{id}", Params: []string{"query:page"}}}
+	wantRemovedParams := []paramChange{{Method: "POST", Pattern: "https://a.com/login", Params: []string{"form:remember"}}}
+	wantChanged := []methodChange{{Pattern: "https://a.com/items", OldMethods: []string{"GET"}, NewMethods: []string{"DELETE", "GET"}}}
+
+	if !reflect.DeepEqual(result.Added, wantAdded) {
+		t.Errorf("Added = %+v, want %+v", result.Added, wantAdded)
+	}
+	if !reflect.DeepEqual(result.Removed, wantRemoved) {
+		t.Errorf("Removed = %+v, want %+v", result.Removed, wantRemoved)
+	}
+	if !reflect.DeepEqual(result.NewParams, wantNewParams) {
+		t.Errorf("NewParams = %+v, want %+v", result.NewParams, wantNewParams)
+	}
+	if !reflect.DeepEqual(result.RemovedParams, wantRemovedParams) {
+		t.Errorf("RemovedParams = %+v, want %+v", result.RemovedParams, wantRemovedParams)
+	}
+	if !reflect.DeepEqual(result.ChangedMethods, wantChanged) {
+		t.Errorf("ChangedMethods = %+v, want %+v", result.ChangedMethods, wantChanged)
+	}
+}
+
+func TestParseDiffArgs(t *testing.T) {
+	var output bytes.Buffer
+	opts, err := parseDiffArgs([]string{"old.json", "new.json"}, &output)
+	if err != nil || *opts != (diffOptions{Format: "text", OldPath: "old.json", NewPath: "new.json"}) {
+		t.Errorf("default options = %+v, %v", opts, err)
+	}
+	opts, err = parseDiffArgs([]string{"-format", "json", "old.json", "new.json"}, &output)
+	if err != nil || opts.Format != "json" {
+		t.Errorf("json options = %+v, %v", opts, err)
+	}
+
+	for _, args := range [][]string{
+		{"-format", "csv", "old.json", "new.json"},
+		{"-format", "JSON", "old.json", "new.json"},
+		{"old.json"},
+		{"-unknown", "old.json", "new.json"},
+	} {
+		output.Reset()
+		if _, err := parseDiffArgs(args, &output); err == nil {
+			t.Errorf("parseDiffArgs(%q) accepted", args)
+		}
+		if !strings.Contains(output.String(), "Usage: flamingo diff") {
+			t.Errorf("parseDiffArgs(%q) output = %q, want usage", args, output.String())
+		}
+	}
+
+	if _, err := parseDiffArgs([]string{"-h"}, &output); !errors.Is(err, flag.ErrHelp) {
+		t.Errorf("-h error = %v", err)
+	}
+}
@@ -0,0 +1,344 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/chromedp/cdproto/browser"
+	"github.com/chromedp/cdproto/cdp"
+	"github.com/chromedp/cdproto/emulation"
+	"github.com/chromedp/chromedp"
+)
+
+// ClientHints User-Agent Client Hints（Sec-CH-UA-* 请求头和 navigator.userAgentData）
+type ClientHints struct {
+	Brands          map[string]string `json:"brands"` // 品牌 -> 主版本号
+	Platform        string            `json:"platform"`
+	PlatformVersion string            `json:"platform_version"`
+	Architecture    string            `json:"architecture"`
+	Bitness         string            `json:"bitness"`
+	Model           string            `json:"model"`
+	Mobile          bool              `json:"mobile"`
+}
+
+// Geolocation 地理位置
+type Geolocation struct {
+	Latitude  float64 `json:"latitude"`
+	Longitude float64 `json:"longitude"`
+	Accuracy  float64 `json:"accuracy"`
+}
+
+// EmulationProfile 设备仿真配置
+type EmulationProfile struct {
+	Name              string       `json:"name"`
+	Width             int64        `json:"width"`
+	Height            int64        `json:"height"`
+	DeviceScaleFactor float64      `json:"device_scale_factor"`
+	Mobile            bool         `json:"mobile"` // 移动端视口（meta viewport、滚动条等）
+	Touch             bool         `json:"touch"`
+	UserAgent         string       `json:"user_agent"`
+	Platform          string       `json:"platform"` // navigator.platform
+	ClientHints       *ClientHints `json:"client_hints"`
+	AcceptLanguage    string       `json:"accept_language"` // 同时决定 navigator.languages
+	Locale            string       `json:"locale"`          // ICU 区域设置，影响日期、数字格式
+	Timezone          string       `json:"timezone"`        // IANA 时区，如 Asia/Shanghai
+	Geolocation       *Geolocation `json:"geolocation"`
+	matchBrowser      bool         // 内置配置：User-Agent 和 Client Hints 中的 Chrome 版本替换为真实浏览器的版本
+}
+
+// chromeVersionRe User-Agent 中的 Chrome 版本
+var chromeVersionRe = regexp.MustCompile(`Chrome/[\d.]+`)
+
+// builtinEmulationProfiles 内置仿真配置
+var builtinEmulationProfiles = map[string]*EmulationProfile{
+	"desktop": {
+		Name:              "desktop",
+		Width:             1920,
+		Height:            1080,
+		DeviceScaleFactor: 1,
+		UserAgent:         "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/138.0.0.0 Safari/537.36",
+		Platform:          "Win32",
+		ClientHints: &ClientHints{
+			Brands:          map[string]string{"Google Chrome": "138", "Chromium": "138", "Not)A;Brand": "8"},
+			Platform:        "Windows",
+			PlatformVersion: "10.0.0",
+			Architecture:    "x86",
+			Bitness:         "64",
+		},
+		AcceptLanguage: "en-US,en;q=0.9",
+		Locale:         "en-US",
+		Timezone:       "America/New_York",
+		matchBrowser:   true,
+	},
+	"iphone": {
+		Name:              "iphone",
+		Width:             390,
+		Height:            844,
+		DeviceScaleFactor: 3,
+		Mobile:            true,
+		Touch:             true,
+		UserAgent:         "Mozilla/5.0 (iPhone; CPU iPhone OS 17_5 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.5 Mobile/15E148 Safari/604.1",
+		Platform:          "iPhone",
+		AcceptLanguage:    "en-US,en;q=0.9",
+		Locale:            "en-US",
+		Timezone:          "America/Los_Angeles",
+	},
+	"android-tablet": {
+		Name:              "android-tablet",
+		Width:             800,
+		Height:            1280,
+		DeviceScaleFactor: 2,
+		Mobile:            true,
+		Touch:             true,
+		UserAgent:         "Mozilla/5.0 (Linux; Android 13; SM-X700) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/138.0.0.0 Safari/537.36",
+		Platform:          "Linux armv81",
+		ClientHints: &ClientHints{
+			Brands:          map[string]string{"Google Chrome": "138", "Chromium": "138", "Not)A;Brand": "8"},
+			Platform:        "Android",
+			PlatformVersion: "13.0.0",
+			Model:           "SM-X700",
+		},
+		AcceptLanguage: "en-US,en;q=0.9",
+		Locale:         "en-US",
+		Timezone:       "America/Chicago",
+		matchBrowser:   true,
+	},
+}
+
+// loadEmulationProfiles 按名称获取仿真配置，配置文件（JSON 数组）中的同名配置覆盖内置配置
+func loadEmulationProfiles(names, path string) ([]*EmulationProfile, error) {
+	available := make(map[string]*EmulationProfile, len(builtinEmulationProfiles))
+	for name, profile := range builtinEmulationProfiles {
+		available[name] = profile
+	}
+	if path != "" {
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		var custom []*EmulationProfile
+		if err := json.Unmarshal(content, &custom); err != nil {
+			return nil, fmt.Errorf("parse %s: %w", path, err)
+		}
+		for i, profile := range custom {
+			if profile.Name == "" || profile.Width <= 0 || profile.Height <= 0 {
+				return nil, fmt.Errorf("%s: profile %d requires name, width and height", path, i+1)
+			}
+			if profile.DeviceScaleFactor == 0 {
+				profile.DeviceScaleFactor = 1
+			}
+			available[strings.ToLower(profile.Name)] = profile
+		}
+	}
+
+	profiles := make([]*EmulationProfile, 0)
+	seen := make(map[string]bool)
+	for _, name := range strings.Split(names, ",") {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" || seen[name] {
+			continue
+		}
+		seen[name] = true
+		profile, ok := available[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown emulation profile %q", name)
+		}
+		profiles = append(profiles, profile)
+	}
+	return profiles, nil
+}
+
+// WithUserAgent 使用指定的 User-Agent，不再发送与之不符的 Client Hints
+func (p *EmulationProfile) WithUserAgent(ua string) *EmulationProfile {
+	profile := *p
+	profile.UserAgent = ua
+	profile.ClientHints = nil
+	profile.matchBrowser = false
+	return &profile
+}
+
+// withBrowserVersion 将内置配置中的 Chrome 版本替换为真实浏览器的版本，与隐身层的 User-Agent 保持一致
+func (p *EmulationProfile) withBrowserVersion(ctx context.Context) (*EmulationProfile, error) {
+	if p == nil || !p.matchBrowser {
+		return p, nil
+	}
+	id, err := resolveBrowserIdentity(ctx)
+	if err != nil {
+		return p, err
+	}
+	profile := *p
+	profile.matchBrowser = false
+	profile.UserAgent = chromeVersionRe.ReplaceAllString(p.UserAgent, "Chrome/"+id.Major+".0.0.0")
+	if p.ClientHints != nil {
+		hints := *p.ClientHints
+		hints.Brands = make(map[string]string, len(p.ClientHints.Brands))
+		for brand, version := range p.ClientHints.Brands {
+			if brand == "Google Chrome" || brand == "Chromium" {
+				version = id.Major
+			}
+			hints.Brands[brand] = version
+		}
+		profile.ClientHints = &hints
+	}
+	return &profile, nil
+}
+
+// Languages navigator.languages 取值，由 Accept-Language 去掉权重得到
+func (p *EmulationProfile) Languages() []string {
+	languages := make([]string, 0)
+	for _, part := range strings.Split(p.AcceptLanguage, ",") {
+		if language, _, _ := strings.Cut(part, ";"); strings.TrimSpace(language) != "" {
+			languages = append(languages, strings.TrimSpace(language))
+		}
+	}
+	return languages
+}
+
+// userAgentMetadata 转换为 CDP 的 UserAgentMetadata
+func (h *ClientHints) userAgentMetadata() *emulation.UserAgentMetadata {
+	metadata := &emulation.UserAgentMetadata{
+		Platform:        h.Platform,
+		PlatformVersion: h.PlatformVersion,
+		Architecture:    h.Architecture,
+		Bitness:         h.Bitness,
+		Model:           h.Model,
+		Mobile:          h.Mobile,
+	}
+	brands := make([]string, 0, len(h.Brands))
+	for brand := range h.Brands {
+		brands = append(brands, brand)
+	}
+	sort.Strings(brands)
+	for _, brand := range brands {
+		version := h.Brands[brand]
+		metadata.Brands = append(metadata.Brands, &emulation.UserAgentBrandVersion{Brand: brand, Version: version})
+		metadata.FullVersionList = append(metadata.FullVersionList, &emulation.UserAgentBrandVersion{Brand: brand, Version: version + ".0.0.0"})
+	}
+	return metadata
+}
+
+// Apply 在标签页中应用仿真配置：视口、触屏、User-Agent 和 Client Hints、语言、时区和地理位置
+func (p *EmulationProfile) Apply() chromedp.Action {
+	return chromedp.ActionFunc(func(ctx context.Context) error {
+		if p == nil {
+			return nil
+		}
+		if err := emulation.SetDeviceMetricsOverride(p.Width, p.Height, p.DeviceScaleFactor, p.Mobile).
+			WithScreenWidth(p.Width).
+			WithScreenHeight(p.Height).
+			Do(ctx); err != nil {
+			return err
+		}
+		touch := emulation.SetTouchEmulationEnabled(p.Touch)
+		if p.Touch {
+			touch = touch.WithMaxTouchPoints(5)
+		}
+		if err := touch.Do(ctx); err != nil {
+			return err
+		}
+		if p.UserAgent != "" || p.AcceptLanguage != "" || p.Platform != "" {
+			// 未设置 User-Agent 时使用真实浏览器版本的 User-Agent，语言和平台仍然生效
+			userAgent := p.UserAgent
+			if userAgent == "" {
+				id, err := resolveBrowserIdentity(ctx)
+				if err != nil {
+					return err
+				}
+				userAgent = id.UserAgent
+			}
+			override := emulation.SetUserAgentOverride(userAgent).
+				WithAcceptLanguage(p.AcceptLanguage).
+				WithPlatform(p.Platform)
+			if p.ClientHints != nil {
+				override = override.WithUserAgentMetadata(p.ClientHints.userAgentMetadata())
+			}
+			if err := override.Do(ctx); err != nil {
+				return err
+			}
+		}
+		if p.Locale != "" {
+			if err := emulation.SetLocaleOverride().WithLocale(p.Locale).Do(ctx); err != nil {
+				return err
+			}
+		}
+		if p.Timezone != "" {
+			if err := emulation.SetTimezoneOverride(p.Timezone).Do(ctx); err != nil {
+				return err
+			}
+		}
+		if p.Geolocation != nil {
+			// 授予地理位置权限，页面调用 navigator.geolocation 时无需确认
+			c := chromedp.FromContext(ctx)
+			grant := browser.GrantPermissions([]browser.PermissionType{browser.PermissionTypeGeolocation})
+			if c.BrowserContextID != "" {
+				grant = grant.WithBrowserContextID(c.BrowserContextID)
+			}
+			if err := grant.Do(cdp.WithExecutor(ctx, c.Browser)); err != nil {
+				return err
+			}
+			accuracy := p.Geolocation.Accuracy
+			if accuracy == 0 {
+				accuracy = 50
+			}
+			if err := emulation.SetGeolocationOverride().
+				WithLatitude(p.Geolocation.Latitude).
+				WithLongitude(p.Geolocation.Longitude).
+				WithAccuracy(accuracy).
+				Do(ctx); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// FilterHeaders 去掉导航请求头中的 User-Agent 和 Accept-Language，由仿真配置决定
+// 请求记录中的请求头可能来自其它仿真配置，直接使用会覆盖当前配置
+func (p *EmulationProfile) FilterHeaders(headers map[string]interface{}) map[string]interface{} {
+	if p == nil {
+		return headers
+	}
+	result := make(map[string]interface{}, len(headers))
+	for name, value := range headers {
+		if strings.EqualFold(name, "User-Agent") || strings.EqualFold(name, "Accept-Language") {
+			continue
+		}
+		result[name] = value
+	}
+	return result
+}
+
+// crawlProfiles 依次以每个仿真配置爬取，结果合并到同一个请求存储；未配置时直接爬取
+// 各配置共享 ctx 的截止时间，由调用方按 -crawl_total_time 设置
+func crawlProfiles(store *RequestStore, allocCtx context.Context, conf *TabConfig, profiles []*EmulationProfile, progressStats *ProgressStats) {
+	if len(profiles) == 0 {
+		crawl(store, allocCtx, conf, progressStats)
+		return
+	}
+	for _, profile := range profiles {
+		if allocCtx.Err() != nil {
+			return
+		}
+		profileConf := *conf
+		profileConf.Emulation = profile
+		profileConf.Headers = make(map[string]interface{}, len(conf.Headers))
+		for name, value := range conf.Headers {
+			profileConf.Headers[name] = value
+		}
+		if profile.UserAgent != "" {
+			profileConf.Headers["User-Agent"] = profile.UserAgent
+		}
+		if len(profiles) > 1 {
+			// 多个仿真配置时标记首个发现请求的配置
+			store.SetProfile(profile.Name)
+			GetGlobalLogger().Info(fmt.Sprintf("Crawling with emulation profile %s", profile.Name))
+		}
+		crawl(store, allocCtx, &profileConf, progressStats)
+	}
+	store.SetProfile("")
+}
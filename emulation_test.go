@@ -0,0 +1,83 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"sync"
+	"testing"
+)
+
+func TestLoadEmulationProfiles(t *testing.T) {
+	profiles, err := loadEmulationProfiles(" Desktop,iphone,desktop,", "")
+	if err != nil || len(profiles) != 2 || profiles[0].Name != "desktop" || profiles[1].Name != "iphone" {
+		t.Fatalf("loadEmulationProfiles() = %v, %v", profiles, err)
+	}
+	if _, err := loadEmulationProfiles("desktop,watch", ""); err == nil {
+		t.Error("unknown profile accepted")
+	}
+
+	path := filepath.Join(t.TempDir(), "profiles.json")
+	content := `[{"name":"Kiosk","width":1080,"height":1920,"user_agent":"kiosk"},{"name":"desktop","width":1280,"height":720}]`
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	profiles, err = loadEmulationProfiles("kiosk,desktop", path)
+	if err != nil || len(profiles) != 2 {
+		t.Fatalf("loadEmulationProfiles() with file = %v, %v", profiles, err)
+	}
+	if profiles[0].UserAgent != "kiosk" || profiles[0].DeviceScaleFactor != 1 {
+		t.Errorf("custom profile = %+v", profiles[0])
+	}
+	if profiles[1].Width != 1280 || profiles[1].matchBrowser {
+		t.Errorf("overridden builtin profile = %+v", profiles[1])
+	}
+	if builtinEmulationProfiles["desktop"].Width != 1920 {
+		t.Error("builtin profile modified")
+	}
+
+	for _, content := range []string{`[{"name":"x","width":0,"height":1}]`, `{"name":"x"}`} {
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := loadEmulationProfiles("x", path); err == nil {
+			t.Errorf("invalid profile file %s accepted", content)
+		}
+	}
+}
+
+func TestWithBrowserVersion(t *testing.T) {
+	// 预置浏览器版本，不启动浏览器
+	identityOnce.Do(func() { cachedIdentity = &browserIdentity{Major: "141"} })
+	t.Cleanup(func() {
+		identityOnce = sync.Once{}
+		cachedIdentity = nil
+	})
+
+	desktop := builtinEmulationProfiles["desktop"]
+	profile, err := desktop.withBrowserVersion(t.Context())
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/141.0.0.0 Safari/537.36"
+	if profile.UserAgent != want || profile.matchBrowser {
+		t.Errorf("UserAgent = %s, matchBrowser = %v", profile.UserAgent, profile.matchBrowser)
+	}
+	wantBrands := map[string]string{"Google Chrome": "141", "Chromium": "141", "Not)A;Brand": "8"}
+	if !reflect.DeepEqual(profile.ClientHints.Brands, wantBrands) {
+		t.Errorf("Brands = %v", profile.ClientHints.Brands)
+	}
+	if desktop.ClientHints.Brands["Chromium"] != "138" || desktop.UserAgent == profile.UserAgent {
+		t.Error("builtin profile modified")
+	}
+
+	// 非 Chrome 配置和指定了 User-Agent 的配置保持不变
+	iphone := builtinEmulationProfiles["iphone"]
+	if got, _ := iphone.withBrowserVersion(t.Context()); got != iphone {
+		t.Error("iphone profile changed")
+	}
+	custom := desktop.WithUserAgent("custom")
+	if got, _ := custom.withBrowserVersion(t.Context()); got != custom || got.ClientHints != nil {
+		t.Errorf("profile with -ua changed: %+v", got)
+	}
+}
@@ -0,0 +1,244 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+
+	"github.com/chromedp/cdproto/fetch"
+	"github.com/chromedp/chromedp"
+)
+
+// HeaderRule 按主机作用域注入的请求头
+type HeaderRule struct {
+	Host  string // 主机，"*.example.com" 匹配该域及其子域，"*" 匹配所有主机
+	Name  string
+	Value string
+}
+
+// Match 判断主机是否在作用域内
+func (r HeaderRule) Match(host string) bool {
+	return hostMatch(r.Host, host)
+}
+
+// hostMatch 判断主机是否匹配作用域：精确主机名、"*.example.com"（该域及其子域）或 "*"
+func hostMatch(pattern, host string) bool {
+	host = strings.ToLower(host)
+	switch {
+	case pattern == "*":
+		return true
+	case strings.HasPrefix(pattern, "*."):
+		domain := pattern[2:]
+		return host == domain || strings.HasSuffix(host, "."+domain)
+	}
+	return host == pattern
+}
+
+// splitHostScope 拆分 "[host] rest" 格式的作用域前缀，未指定时使用 defaultHost
+func splitHostScope(line, defaultHost string) (string, string, error) {
+	line = strings.TrimSpace(line)
+	if !strings.HasPrefix(line, "[") {
+		return strings.ToLower(defaultHost), line, nil
+	}
+	end := strings.Index(line, "]")
+	if end < 0 {
+		return "", "", fmt.Errorf("invalid %q: unclosed host scope", line)
+	}
+	return strings.ToLower(strings.TrimSpace(line[1:end])), strings.TrimSpace(line[end+1:]), nil
+}
+
+// hostOf 获取 URL 的主机名（小写，不含端口）
+func hostOf(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return ""
+	}
+	return strings.ToLower(u.Hostname())
+}
+
+// parseHeaderRule 解析 "Name: value" 或 "[host] Name: value"，未指定主机时作用于 defaultHost
+func parseHeaderRule(line, defaultHost string) (HeaderRule, error) {
+	var rule HeaderRule
+	host, rest, err := splitHostScope(line, defaultHost)
+	if err != nil {
+		return rule, err
+	}
+	name, value, found := strings.Cut(rest, ":")
+	name = strings.TrimSpace(name)
+	if !found || name == "" || host == "" {
+		return rule, fmt.Errorf("invalid header %q, expected \"[host] Name: value\"", line)
+	}
+	rule.Host = host
+	rule.Name = http.CanonicalHeaderKey(name)
+	rule.Value = strings.TrimSpace(value)
+	return rule, nil
+}
+
+// loadHeaderFile 加载请求头文件，每行一个请求头，忽略空行和 # 开头的注释
+func loadHeaderFile(path, defaultHost string) ([]HeaderRule, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	rules := make([]HeaderRule, 0)
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		rule, err := parseHeaderRule(line, defaultHost)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		rules = append(rules, rule)
+	}
+	return rules, scanner.Err()
+}
+
+// SetHeaderRules 设置按主机作用域注入的请求头
+func (s *Session) SetHeaderRules(rules []HeaderRule) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.headerRules = rules
+	s.headerNames = make(map[string]bool, len(rules))
+	for _, rule := range rules {
+		s.headerNames[strings.ToLower(rule.Name)] = true
+	}
+}
+
+// HeadersFor 获取指定 URL 需要注入的请求头，后定义的规则优先
+func (s *Session) HeadersFor(rawURL string) map[string]string {
+	if s == nil {
+		return nil
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if len(s.headerRules) == 0 {
+		return nil
+	}
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil
+	}
+	headers := make(map[string]string)
+	for _, rule := range s.headerRules {
+		if rule.Match(u.Hostname()) {
+			headers[rule.Name] = rule.Value
+		}
+	}
+	return headers
+}
+
+// HasHeaderRules 是否设置了作用域请求头
+func (s *Session) HasHeaderRules() bool {
+	if s == nil {
+		return false
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return len(s.headerRules) > 0
+}
+
+// isScopedHeader 判断请求头是否由作用域规则管理
+func (s *Session) isScopedHeader(name string) bool {
+	if s == nil {
+		return false
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.headerNames[strings.ToLower(name)]
+}
+
+// continuePaused 放行被拦截的请求，应用改写规则并注入该请求主机作用域内的请求头；需要客户端证书的请求改由 Go 端发送
+func continuePaused(ctx context.Context, ev *fetch.EventRequestPaused, conf *TabConfig) error {
+	session := conf.Session
+	ev, mock, rewritten, strip := rewriteConfig.rewriteRequest(ev)
+	if mock != nil {
+		return mock.fulfill(ctx, ev.RequestID)
+	}
+	if tlsSettings.NeedsClientCert(ev.Request.URL) {
+		return fulfillWithClientCert(ctx, ev, conf.HTTPClient)
+	}
+	params := fetch.ContinueRequest(ev.RequestID)
+	if rewritten {
+		// 改写后的 URL 对页面不可见，页面中的 URL 和爬取结果仍使用原 URL
+		params = params.WithURL(ev.Request.URL)
+	}
+	if len(strip) > 0 {
+		pendingStrips.Store(ev.RequestID, strip)
+	}
+	// 可能收到认证质询的请求同样在响应阶段拦截，以便请求完成后删除质询记录
+	if len(strip) > 0 || mayRequireAuth(session, ev.Request.URL) {
+		params = params.WithInterceptResponse(true)
+	}
+	scoped := session.HeadersFor(ev.Request.URL)
+	if len(scoped) == 0 && !rewritten {
+		return params.Do(ctx)
+	}
+
+	entries := make([]*fetch.HeaderEntry, 0, len(ev.Request.Headers)+len(scoped))
+	for name, value := range ev.Request.Headers {
+		if session.isScopedHeader(name) {
+			continue
+		}
+		entries = append(entries, &fetch.HeaderEntry{Name: name, Value: fmt.Sprint(value)})
+	}
+	for name, value := range scoped {
+		entries = append(entries, &fetch.HeaderEntry{Name: name, Value: value})
+	}
+	return params.WithHeaders(entries).Do(ctx)
+}
+
+// enableSessionInterception 在独立标签页（如登录标签页）中开启请求拦截，注入作用域请求头、响应 HTTP 认证并转发需要客户端证书的请求
+func enableSessionInterception(ctx context.Context, conf *TabConfig) chromedp.Action {
+	session := conf.Session
+	return chromedp.ActionFunc(func(actionCtx context.Context) error {
+		handleAuth := handleAuthRequests(session)
+		if !session.HasHeaderRules() && !handleAuth && !tlsSettings.HasClientCert() && rewriteConfig == nil {
+			return nil
+		}
+		chromedp.ListenTarget(ctx, func(ev interface{}) {
+			switch ev := ev.(type) {
+			case *fetch.EventRequestPaused:
+				go func() {
+					if isResponseStage(ev) {
+						_ = continueResponse(actionCtx, ev)
+						return
+					}
+					_ = continuePaused(actionCtx, ev, conf)
+				}()
+			case *fetch.EventAuthRequired:
+				go func() {
+					_ = continueWithAuth(actionCtx, ev, session)
+				}()
+			}
+		})
+		return fetch.Enable().WithHandleAuthRequests(handleAuth).Do(actionCtx)
+	})
+}
+
+// scopedTransport Go 端 HTTP 客户端的传输层，按请求主机注入作用域请求头
+type scopedTransport struct {
+	base    http.RoundTripper
+	session *Session
+}
+
+// RoundTrip 实现 http.RoundTripper
+func (t *scopedTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	scoped := t.session.HeadersFor(req.URL.String())
+	if len(scoped) == 0 {
+		return t.base.RoundTrip(req)
+	}
+	req = req.Clone(req.Context())
+	for name, value := range scoped {
+		req.Header.Set(name, value)
+	}
+	return t.base.RoundTrip(req)
+}
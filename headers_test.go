@@ -0,0 +1,185 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/chromedp/cdproto/cdp"
+	"github.com/chromedp/cdproto/fetch"
+	"github.com/chromedp/cdproto/network"
+)
+
+func TestHostMatch(t *testing.T) {
+	tests := []struct {
+		pattern, host string
+		want          bool
+	}{
+		{"*", "any.example.org", true},
+		{"app.example.com", "APP.example.com", true},
+		{"app.example.com", "api.example.com", false},
+		{"*.example.com", "example.com", true},
+		{"*.example.com", "a.b.example.com", true},
+		{"*.example.com", "badexample.com", false},
+	}
+	for _, tt := range tests {
+		if got := hostMatch(tt.pattern, tt.host); got != tt.want {
+			t.Errorf("hostMatch(%q, %q) = %v, want %v", tt.pattern, tt.host, got, tt.want)
+		}
+	}
+}
+
+func TestParseHeaderRule(t *testing.T) {
+	tests := []struct {
+		line    string
+		want    HeaderRule
+		wantErr bool
+	}{
+		{"x-api-key: k1", HeaderRule{Host: "app.example.com", Name: "X-Api-Key", Value: "k1"}, false},
+		{"[*.Example.com] Authorization: Bearer a:b", HeaderRule{Host: "*.example.com", Name: "Authorization", Value: "Bearer a:b"}, false},
+		{"[*] X-Empty:", HeaderRule{Host: "*", Name: "X-Empty"}, false},
+		{"[api.example.com X-A: 1", HeaderRule{}, true},
+		{"no colon", HeaderRule{}, true},
+		{"[] X-A: 1", HeaderRule{}, true},
+	}
+	for _, tt := range tests {
+		got, err := parseHeaderRule(tt.line, "App.example.com")
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("parseHeaderRule(%q) = %+v, %v, want %+v", tt.line, got, err, tt.want)
+		}
+	}
+}
+
+func TestLoadHeaderFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "headers.txt")
+	content := "# comment\n\nX-A: 1\n[api.example.com] X-B: 2\n"
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	rules, err := loadHeaderFile(path, "app.example.com")
+	want := []HeaderRule{{"app.example.com", "X-A", "1"}, {"api.example.com", "X-B", "2"}}
+	if err != nil || !reflect.DeepEqual(rules, want) {
+		t.Errorf("loadHeaderFile() = %+v, %v", rules, err)
+	}
+
+	if err := os.WriteFile(path, []byte("bad line\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := loadHeaderFile(path, "app.example.com"); err == nil {
+		t.Error("invalid header file accepted")
+	}
+}
+
+// scopedSession 创建带作用域请求头的会话
+func scopedSession() *Session {
+	session := NewSession()
+	session.SetHeaderRules([]HeaderRule{
+		{Host: "*.example.com", Name: "X-Tenant", Value: "a"},
+		{Host: "api.example.com", Name: "X-Tenant", Value: "b"},
+		{Host: "api.example.com", Name: "Authorization", Value: "Bearer t"},
+	})
+	return session
+}
+
+func TestSessionHeadersFor(t *testing.T) {
+	session := scopedSession()
+	tests := map[string]map[string]string{
+		"https://app.example.com/":     {"X-Tenant": "a"},
+		"https://api.example.com/v1":   {"X-Tenant": "b", "Authorization": "Bearer t"},
+		"https://cdn.example.org/a.js": {},
+	}
+	for rawURL, want := range tests {
+		if got := session.HeadersFor(rawURL); !reflect.DeepEqual(got, want) {
+			t.Errorf("HeadersFor(%s) = %v, want %v", rawURL, got, want)
+		}
+	}
+	var nilSession *Session
+	if nilSession.HeadersFor("https://app.example.com/") != nil || nilSession.HasHeaderRules() {
+		t.Error("nil session returned headers")
+	}
+}
+
+func TestSessionApplyHeaders(t *testing.T) {
+	session := scopedSession()
+	headers := map[string]interface{}{"User-Agent": "flamingo", "x-tenant": "leaked", "authorization": "Basic x"}
+
+	// 作用域外且会话中没有 cookie 时原样返回
+	got := session.ApplyHeaders("https://cdn.example.org/", headers)
+	if !reflect.DeepEqual(got, headers) {
+		t.Errorf("out of scope ApplyHeaders() = %v, want %v", got, headers)
+	}
+	got = session.ApplyHeaders("https://api.example.com/", headers)
+	want := map[string]interface{}{"User-Agent": "flamingo", "X-Tenant": "b", "Authorization": "Bearer t"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("in scope ApplyHeaders() = %v, want %v", got, want)
+	}
+
+	got = session.BrowserHeaders(headers)
+	want = map[string]interface{}{"User-Agent": "flamingo"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("BrowserHeaders() = %v, want %v", got, want)
+	}
+}
+
+func TestContinuePausedScopedHeaders(t *testing.T) {
+	conf := &TabConfig{Session: scopedSession()}
+	executor := &recordingExecutor{}
+	ctx := cdp.WithExecutor(context.Background(), executor)
+
+	ev := pausedEvent("GET", "https://api.example.com/v1", "XHR", network.Headers{"Accept": "*/*", "x-tenant": "page"})
+	if err := continuePaused(ctx, ev, conf); err != nil {
+		t.Fatal(err)
+	}
+	var params fetch.ContinueRequestParams
+	if err := json.Unmarshal(executor.params, &params); err != nil {
+		t.Fatal(err)
+	}
+	got := make(map[string]string)
+	for _, header := range params.Headers {
+		got[header.Name] = header.Value
+	}
+	want := map[string]string{"Accept": "*/*", "X-Tenant": "b", "Authorization": "Bearer t"}
+	if executor.method != fetch.CommandContinueRequest || !reflect.DeepEqual(got, want) {
+		t.Errorf("%s headers = %v, want %v", executor.method, got, want)
+	}
+
+	// 作用域外的请求原样放行
+	ev = pausedEvent("GET", "https://cdn.example.org/a.js", "Script", network.Headers{"Accept": "*/*"})
+	if err := continuePaused(ctx, ev, conf); err != nil {
+		t.Fatal(err)
+	}
+	params = fetch.ContinueRequestParams{}
+	if err := json.Unmarshal(executor.params, &params); err != nil {
+		t.Fatal(err)
+	}
+	if params.Headers != nil {
+		t.Errorf("out of scope headers = %v", params.Headers)
+	}
+}
+
+func TestScopedTransport(t *testing.T) {
+	var got http.Header
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r.Header.Clone()
+	}))
+	defer server.Close()
+
+	session := NewSession()
+	session.SetHeaderRules([]HeaderRule{{Host: "127.0.0.1", Name: "X-Api-Key", Value: "k1"}})
+	client := &http.Client{Transport: &scopedTransport{base: http.DefaultTransport, session: session}}
+	req, _ := http.NewRequest("GET", server.URL, nil)
+	req.Header.Set("X-Api-Key", "original")
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if got.Get("X-Api-Key") != "k1" || req.Header.Get("X-Api-Key") != "original" {
+		t.Errorf("sent %q, caller request modified to %q", got.Get("X-Api-Key"), req.Header.Get("X-Api-Key"))
+	}
+}
@@ -0,0 +1,280 @@
+package main
+
+import (
+	"context"
+	"crypto/md5"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+
+	"github.com/chromedp/cdproto/fetch"
+)
+
+// HTTPCredential 按主机作用域配置的 HTTP 认证凭据（basic、digest、NTLM）
+type HTTPCredential struct {
+	Host     string // 主机，格式同 HeaderRule.Host
+	Username string
+	Password string
+}
+
+// parseHTTPCredential 解析 "user:pass" 或 "[host] user:pass"，未指定主机时作用于 defaultHost
+// NTLM 域账号写作 "DOMAIN\\user:pass"
+func parseHTTPCredential(line, defaultHost string) (HTTPCredential, error) {
+	var cred HTTPCredential
+	host, rest, err := splitHostScope(line, defaultHost)
+	if err != nil {
+		return cred, err
+	}
+	username, password, found := strings.Cut(rest, ":")
+	if !found || username == "" || host == "" {
+		return cred, fmt.Errorf("invalid http auth %q, expected \"[host] user:pass\"", line)
+	}
+	return HTTPCredential{Host: host, Username: username, Password: password}, nil
+}
+
+// SetCredentials 设置 HTTP 认证凭据
+func (s *Session) SetCredentials(creds []HTTPCredential) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.credentials = creds
+}
+
+// HasCredentials 是否设置了 HTTP 认证凭据
+func (s *Session) HasCredentials() bool {
+	if s == nil {
+		return false
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return len(s.credentials) > 0
+}
+
+// CredentialFor 获取指定 URL 的认证凭据，后定义的优先
+func (s *Session) CredentialFor(rawURL string) (HTTPCredential, bool) {
+	if s == nil {
+		return HTTPCredential{}, false
+	}
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return HTTPCredential{}, false
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	for i := len(s.credentials) - 1; i >= 0; i-- {
+		if hostMatch(s.credentials[i].Host, u.Hostname()) {
+			return s.credentials[i], true
+		}
+	}
+	return HTTPCredential{}, false
+}
+
+// authAttempts 已提供过凭据的请求，key: 质询来源:fetch.RequestID；同一请求再次收到质询说明凭据无效
+// 可能收到质询的请求在响应阶段被拦截，由 continueResponse 删除记录
+var authAttempts sync.Map
+
+// authAttemptKey 认证质询记录的键
+func authAttemptKey(source fetch.AuthChallengeSource, id fetch.RequestID) string {
+	return string(source) + ":" + string(id)
+}
+
+// clearAuthAttempts 请求完成后删除其认证质询记录
+func clearAuthAttempts(id fetch.RequestID) {
+	authAttempts.Delete(authAttemptKey(fetch.AuthChallengeSourceServer, id))
+	authAttempts.Delete(authAttemptKey(fetch.AuthChallengeSourceProxy, id))
+}
+
+// mayRequireAuth 判断请求是否可能收到由本程序响应的认证质询：配置了代理凭据，或 URL 在 HTTP 认证凭据的作用域内
+func mayRequireAuth(session *Session, rawURL string) bool {
+	if upstreamProxy.HasCredentials() {
+		return true
+	}
+	_, ok := session.CredentialFor(rawURL)
+	return ok
+}
+
+// continueWithAuth 响应浏览器的 HTTP 认证和代理认证质询
+// 同一请求只提供一次凭据，凭据错误时取消认证，避免反复质询
+func continueWithAuth(ctx context.Context, ev *fetch.EventAuthRequired, session *Session) error {
+	response := &fetch.AuthChallengeResponse{Response: fetch.AuthChallengeResponseResponseDefault}
+	if ev.AuthChallenge == nil {
+		return fetch.ContinueWithAuth(ev.RequestID, response).Do(ctx)
+	}
+
+	var username, password string
+	found := false
+	if ev.AuthChallenge.Source == fetch.AuthChallengeSourceProxy {
+		if found = upstreamProxy.HasCredentials(); found {
+			username, password = upstreamProxy.Credential()
+		}
+	} else if cred, ok := session.CredentialFor(ev.Request.URL); ok {
+		username, password, found = cred.Username, cred.Password, true
+	}
+	if found {
+		if _, tried := authAttempts.LoadOrStore(authAttemptKey(ev.AuthChallenge.Source, ev.RequestID), true); tried {
+			GetGlobalLogger().WarnWithURL(fmt.Sprintf("%s %s authentication failed", ev.AuthChallenge.Source, ev.AuthChallenge.Scheme), ev.Request.URL)
+			response.Response = fetch.AuthChallengeResponseResponseCancelAuth
+		} else {
+			response.Response = fetch.AuthChallengeResponseResponseProvideCredentials
+			response.Username = username
+			response.Password = password
+		}
+	}
+	return fetch.ContinueWithAuth(ev.RequestID, response).Do(ctx)
+}
+
+// authTransport Go 端 HTTP 客户端的传输层，收到 401 质询后使用凭据重试一次
+// 支持 basic 和 digest，NTLM 需要连接级握手，仅由浏览器处理
+type authTransport struct {
+	base    http.RoundTripper
+	session *Session
+}
+
+// RoundTrip 实现 http.RoundTripper
+func (t *authTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := t.base.RoundTrip(req)
+	if err != nil || resp.StatusCode != http.StatusUnauthorized || req.Header.Get("Authorization") != "" {
+		return resp, err
+	}
+	cred, ok := t.session.CredentialFor(req.URL.String())
+	if !ok {
+		return resp, nil
+	}
+	authorization := challengeResponse(resp.Header.Values("WWW-Authenticate"), cred, req)
+	if authorization == "" {
+		return resp, nil
+	}
+
+	retry := req.Clone(req.Context())
+	if req.Body != nil {
+		if req.GetBody == nil {
+			return resp, nil
+		}
+		if retry.Body, err = req.GetBody(); err != nil {
+			return resp, nil
+		}
+	}
+	_, _ = io.Copy(io.Discard, resp.Body)
+	resp.Body.Close()
+	retry.Header.Set("Authorization", authorization)
+	return t.base.RoundTrip(retry)
+}
+
+// challengeResponse 根据 WWW-Authenticate 质询生成 Authorization 头，优先使用 digest
+func challengeResponse(challenges []string, cred HTTPCredential, req *http.Request) string {
+	basic := false
+	for _, challenge := range challenges {
+		scheme, params := parseChallenge(challenge)
+		switch scheme {
+		case "digest":
+			if value := digestAuthorization(cred, req, params); value != "" {
+				return value
+			}
+		case "basic":
+			basic = true
+		}
+	}
+	if basic {
+		r := &http.Request{Header: make(http.Header)}
+		r.SetBasicAuth(cred.Username, cred.Password)
+		return r.Header.Get("Authorization")
+	}
+	return ""
+}
+
+// parseChallenge 解析单个认证质询，返回小写的认证方案和参数
+func parseChallenge(challenge string) (string, map[string]string) {
+	scheme, rest, _ := strings.Cut(strings.TrimSpace(challenge), " ")
+	params := make(map[string]string)
+	for rest != "" {
+		rest = strings.TrimLeft(rest, " ,")
+		name, value, found := strings.Cut(rest, "=")
+		if !found {
+			break
+		}
+		name = strings.ToLower(strings.TrimSpace(name))
+		value = strings.TrimSpace(value)
+		if strings.HasPrefix(value, `"`) {
+			// 带引号的值，可能包含逗号
+			end := strings.Index(value[1:], `"`)
+			if end < 0 {
+				params[name] = value[1:]
+				break
+			}
+			params[name] = value[1 : end+1]
+			rest = value[end+2:]
+		} else {
+			value, rest, _ = strings.Cut(value, ",")
+			params[name] = strings.TrimSpace(value)
+		}
+	}
+	return strings.ToLower(scheme), params
+}
+
+// digestAuthorization 按 RFC 7616 生成 digest 认证头，支持 MD5、SHA-256 及其 -sess 变体和 qop=auth
+func digestAuthorization(cred HTTPCredential, req *http.Request, params map[string]string) string {
+	cnonceBytes := make([]byte, 8)
+	_, _ = rand.Read(cnonceBytes)
+	return digestAuthorizationWith(cred, req.Method, req.URL.RequestURI(), params, hex.EncodeToString(cnonceBytes))
+}
+
+// digestAuthorizationWith 使用指定的客户端随机数生成 digest 认证头
+func digestAuthorizationWith(cred HTTPCredential, method, uri string, params map[string]string, cnonce string) string {
+	algorithm := params["algorithm"]
+	if algorithm == "" {
+		algorithm = "MD5"
+	}
+	var newHash func() hash.Hash
+	switch strings.TrimSuffix(strings.ToUpper(algorithm), "-SESS") {
+	case "MD5":
+		newHash = md5.New
+	case "SHA-256":
+		newHash = sha256.New
+	default:
+		return ""
+	}
+	h := func(s string) string {
+		hasher := newHash()
+		hasher.Write([]byte(s))
+		return hex.EncodeToString(hasher.Sum(nil))
+	}
+
+	realm, nonce := params["realm"], params["nonce"]
+	nc := "00000001"
+
+	ha1 := h(cred.Username + ":" + realm + ":" + cred.Password)
+	if strings.HasSuffix(strings.ToUpper(algorithm), "-SESS") {
+		ha1 = h(ha1 + ":" + nonce + ":" + cnonce)
+	}
+	ha2 := h(method + ":" + uri)
+
+	qop := ""
+	for _, option := range strings.Split(params["qop"], ",") {
+		if strings.TrimSpace(option) == "auth" {
+			qop = "auth"
+		}
+	}
+
+	var response string
+	if qop != "" {
+		response = h(strings.Join([]string{ha1, nonce, nc, cnonce, qop, ha2}, ":"))
+	} else {
+		response = h(ha1 + ":" + nonce + ":" + ha2)
+	}
+
+	value := fmt.Sprintf(`Digest username="%s", realm="%s", nonce="%s", uri="%s", algorithm=%s, response="%s"`,
+		cred.Username, realm, nonce, uri, algorithm, response)
+	if qop != "" {
+		value += fmt.Sprintf(`, qop=%s, nc=%s, cnonce="%s"`, qop, nc, cnonce)
+	}
+	if opaque, ok := params["opaque"]; ok {
+		value += fmt.Sprintf(`, opaque="%s"`, opaque)
+	}
+	return value
+}
@@ -0,0 +1,149 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/chromedp/cdproto/cdp"
+	"github.com/chromedp/cdproto/fetch"
+	"github.com/chromedp/cdproto/network"
+)
+
+func TestParseChallenge(t *testing.T) {
+	tests := []struct {
+		challenge  string
+		wantScheme string
+		wantParams map[string]string
+	}{
+		{`Basic realm="Restricted"`, "basic", map[string]string{"realm": "Restricted"}},
+		{
+			`Digest realm="a, b", qop="auth,auth-int", nonce=abc, stale=FALSE`,
+			"digest",
+			map[string]string{"realm": "a, b", "qop": "auth,auth-int", "nonce": "abc", "stale": "FALSE"},
+		},
+		{`NTLM`, "ntlm", map[string]string{}},
+		{`Digest realm="unterminated`, "digest", map[string]string{"realm": "unterminated"}},
+	}
+	for _, tt := range tests {
+		scheme, params := parseChallenge(tt.challenge)
+		if scheme != tt.wantScheme || !reflect.DeepEqual(params, tt.wantParams) {
+			t.Errorf("parseChallenge(%q) = %q, %v, want %q, %v", tt.challenge, scheme, params, tt.wantScheme, tt.wantParams)
+		}
+	}
+}
+
+// RFC 7616 3.9.1 的示例
+func TestDigestAuthorization(t *testing.T) {
+	cred := HTTPCredential{Username: "Mufasa", Password: "Circle of Life"}
+	const challenge = `Digest realm="http-auth@example.org", qop="auth, auth-int", nonce="7ypf/xlj9XXwfDPEoM4URrv/xwf94BcCAzFZH4GiTo0v", opaque="FQhe/qaU925kfnzjCev0ciny7QMkPqMAFRtzCUYo5tdS"`
+	const cnonce = "f2/wE4q74E6zIJEtWaHKaf5wv/H5QzzpXusqGemxURZJ"
+	tests := []struct {
+		algorithm string
+		want      string
+	}{
+		{"MD5", "8ca523f5e9506fed4657c9700eebdbec"},
+		{"SHA-256", "753927fa0e85d155564e2e272a28d1802ca10daf4496794697cf8db5856cb6c1"},
+		{"SHA-512-256", ""},
+	}
+	for _, tt := range tests {
+		_, params := parseChallenge(challenge + ", algorithm=" + tt.algorithm)
+		value := digestAuthorizationWith(cred, http.MethodGet, "/dir/index.html", params, cnonce)
+		if tt.want == "" {
+			if value != "" {
+				t.Errorf("digestAuthorization(%s) = %q, want unsupported", tt.algorithm, value)
+			}
+			continue
+		}
+		scheme, got := parseChallenge(value)
+		if scheme != "digest" || got["response"] != tt.want {
+			t.Errorf("digestAuthorization(%s) response = %q, want %q", tt.algorithm, got["response"], tt.want)
+		}
+		for name, want := range map[string]string{"qop": "auth", "nc": "00000001", "cnonce": cnonce, "opaque": params["opaque"], "uri": "/dir/index.html"} {
+			if got[name] != want {
+				t.Errorf("digestAuthorization(%s) %s = %q, want %q", tt.algorithm, name, got[name], want)
+			}
+		}
+	}
+}
+
+func TestChallengeResponse(t *testing.T) {
+	cred := HTTPCredential{Username: "user", Password: "pass"}
+	req, _ := http.NewRequest(http.MethodGet, "http://example.com/a?b=1", nil)
+	tests := []struct {
+		challenges []string
+		wantPrefix string
+	}{
+		{[]string{`Basic realm="x"`}, "Basic dXNlcjpwYXNz"},
+		{[]string{`Basic realm="x"`, `Digest realm="x", nonce="n"`}, `Digest username="user"`},
+		{[]string{`NTLM`}, ""},
+	}
+	for _, tt := range tests {
+		got := challengeResponse(tt.challenges, cred, req)
+		if !strings.HasPrefix(got, tt.wantPrefix) || (tt.wantPrefix == "" && got != "") {
+			t.Errorf("challengeResponse(%v) = %q, want prefix %q", tt.challenges, got, tt.wantPrefix)
+		}
+	}
+}
+
+func TestContinueWithAuthAttempts(t *testing.T) {
+	session := NewSession()
+	session.SetCredentials([]HTTPCredential{{Host: "app.example.com", Username: "u", Password: "p"}})
+	executor := &recordingExecutor{}
+	ctx := cdp.WithExecutor(context.Background(), executor)
+	challenge := &fetch.EventAuthRequired{
+		RequestID:     "interception-1",
+		Request:       &network.Request{Method: "GET", URL: "https://app.example.com/private"},
+		AuthChallenge: &fetch.AuthChallenge{Source: fetch.AuthChallengeSourceServer, Scheme: "basic"},
+	}
+	answer := func() fetch.AuthChallengeResponseResponse {
+		t.Helper()
+		if err := continueWithAuth(ctx, challenge, session); err != nil {
+			t.Fatal(err)
+		}
+		var params fetch.ContinueWithAuthParams
+		if err := json.Unmarshal(executor.params, &params); err != nil {
+			t.Fatal(err)
+		}
+		return params.AuthChallengeResponse.Response
+	}
+
+	// 第一次质询提供凭据，同一请求再次质询时取消
+	if got := answer(); got != fetch.AuthChallengeResponseResponseProvideCredentials {
+		t.Errorf("first challenge answered with %s", got)
+	}
+	if got := answer(); got != fetch.AuthChallengeResponseResponseCancelAuth {
+		t.Errorf("repeated challenge answered with %s", got)
+	}
+
+	// 作用域内的请求在响应阶段拦截，响应阶段删除质询记录
+	paused := pausedEvent("GET", "https://app.example.com/private", "Document", nil)
+	if err := continuePaused(ctx, paused, &TabConfig{Session: session}); err != nil {
+		t.Fatal(err)
+	}
+	var params fetch.ContinueRequestParams
+	if err := json.Unmarshal(executor.params, &params); err != nil || !params.InterceptResponse {
+		t.Errorf("in scope request not intercepted at response stage: %s", executor.params)
+	}
+	paused.ResponseStatusCode = 200
+	if err := continueResponse(ctx, paused); err != nil {
+		t.Fatal(err)
+	}
+	authAttempts.Range(func(key, _ any) bool {
+		t.Errorf("auth attempt %v not cleared", key)
+		return true
+	})
+
+	// 作用域外的请求不拦截响应
+	other := pausedEvent("GET", "https://cdn.example.org/a.js", "Script", nil)
+	if err := continuePaused(ctx, other, &TabConfig{Session: session}); err != nil {
+		t.Fatal(err)
+	}
+	params = fetch.ContinueRequestParams{}
+	if err := json.Unmarshal(executor.params, &params); err != nil || params.InterceptResponse {
+		t.Errorf("out of scope request intercepted at response stage: %s", executor.params)
+	}
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"net/http"
+	"strings"
+	"time"
+)
+
+// baseTransport Go 端 HTTP 客户端共享的传输层，复用连接池
+var baseTransport = &http.Transport{
+	MaxIdleConns:        200,
+	MaxIdleConnsPerHost: 20,
+	MaxConnsPerHost:     50,
+	IdleConnTimeout:     120 * time.Second,
+	DisableKeepAlives:   false,
+	ForceAttemptHTTP2:   true,
+}
+
+// newSessionClient 创建使用指定会话的 HTTP 客户端：共享会话 cookie jar，按主机注入作用域请求头并响应 HTTP 认证质询
+func newSessionClient(session *Session) *http.Client {
+	client := &http.Client{
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+		Transport: baseTransport,
+		Timeout:   15 * time.Second,
+	}
+	if session != nil {
+		client.Jar = session.Jar()
+		client.Transport = &scopedTransport{
+			base:    &authTransport{base: baseTransport, session: session},
+			session: session,
+		}
+	}
+	return client
+}
+
+// newGoRequest 创建由 client 发送的 Go 端请求并设置请求头
+// 会话中有 cookie 时忽略 Cookie 头，由 cookie jar 按作用域携带；作用域请求头由传输层按主机注入
+func newGoRequest(client *http.Client, method, rawURL string, headers map[string]interface{}) (*http.Request, error) {
+	req, err := http.NewRequest(method, rawURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	for name, value := range headers {
+		strValue, ok := value.(string)
+		if !ok || strValue == "" {
+			continue
+		}
+		if jar, ok := client.Jar.(sessionJar); ok {
+			if (jar.s.HasCookies() && strings.EqualFold(name, "Cookie")) || jar.s.isScopedHeader(name) {
+				continue
+			}
+		}
+		req.Header.Set(name, strValue)
+	}
+	return req, nil
+}
@@ -0,0 +1,243 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	b64 "encoding/base64"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+
+	"github.com/chromedp/cdproto/network"
+)
+
+// 导入请求时丢弃的请求头：由浏览器或传输层自行设置的、条件请求和缓存相关的（否则导航可能得到空的 304 响应），
+// 以及会话凭据（由会话按作用域携带）
+var importDropHeaders = map[string]bool{
+	"host":                true,
+	"content-length":      true,
+	"connection":          true,
+	"keep-alive":          true,
+	"proxy-connection":    true,
+	"transfer-encoding":   true,
+	"upgrade":             true,
+	"te":                  true,
+	"accept-encoding":     true,
+	"if-none-match":       true,
+	"if-modified-since":   true,
+	"if-match":            true,
+	"if-unmodified-since": true,
+	"if-range":            true,
+	"cache-control":       true,
+	"pragma":              true,
+	"cookie":              true,
+	"authorization":       true,
+}
+
+// isImportDropHeader 判断导入或回放的请求头是否丢弃，Sec-Fetch-*、Sec-CH-* 等 Sec- 请求头由浏览器按实际请求设置
+func isImportDropHeader(name string) bool {
+	lower := strings.ToLower(name)
+	return importDropHeaders[lower] || strings.HasPrefix(lower, "sec-") || strings.HasPrefix(lower, ":")
+}
+
+// harFile HAR 文件结构（仅解析需要的字段）
+type harFile struct {
+	Log struct {
+		Entries []struct {
+			Request struct {
+				Method  string `json:"method"`
+				URL     string `json:"url"`
+				Headers []struct {
+					Name  string `json:"name"`
+					Value string `json:"value"`
+				} `json:"headers"`
+				PostData *struct {
+					MimeType string `json:"mimeType"`
+					Text     string `json:"text"`
+				} `json:"postData"`
+			} `json:"request"`
+		} `json:"entries"`
+	} `json:"log"`
+}
+
+// burpItems Burp Suite 导出的 XML 结构
+type burpItems struct {
+	Items []struct {
+		URL     string `xml:"url"`
+		Method  string `xml:"method"`
+		Request struct {
+			Base64 bool   `xml:"base64,attr"`
+			Value  string `xml:",chardata"`
+		} `xml:"request"`
+	} `xml:"item"`
+}
+
+// importedRequest 导入的原始请求
+type importedRequest struct {
+	Method  string
+	URL     string
+	Headers map[string]interface{}
+	Data    string
+}
+
+// importRequests 从 requests.json、HAR 或 Burp XML 文件导入请求
+// 请求中的 Cookie 和 Authorization 加入 session（为 nil 时丢弃），由会话按 cookie 和主机作用域携带
+func importRequests(path string, baseHeaders map[string]interface{}, session *Session) ([]request, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var imported []importedRequest
+	trimmed := bytes.TrimSpace(content)
+	switch {
+	case bytes.HasPrefix(trimmed, []byte("<")):
+		imported, err = parseBurpXML(trimmed)
+	case bytes.HasPrefix(trimmed, []byte("[")):
+		imported, err = parseRequestsJSON(trimmed)
+	default:
+		imported, err = parseHAR(trimmed)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("parse %s: %w", path, err)
+	}
+
+	requests := make([]request, 0, len(imported))
+	for _, item := range imported {
+		if session != nil {
+			session.ImportCredentials(item.URL, item.Headers)
+		}
+		headers := mergeImportHeaders(item.Headers, baseHeaders, item.Data != "")
+		requests = append(requests, geneRequest(strings.ToUpper(item.Method), item.URL, headers, item.Data, "import"))
+	}
+	return requests, nil
+}
+
+// mergeImportHeaders 清理导入的请求头，没有请求体时去掉 Content-Type，并用当前配置的非空请求头覆盖
+func mergeImportHeaders(imported, base map[string]interface{}, hasBody bool) map[string]interface{} {
+	headers := make(map[string]interface{})
+	for name, value := range imported {
+		if isImportDropHeader(name) || (!hasBody && strings.EqualFold(name, "Content-Type")) {
+			continue
+		}
+		headers[http.CanonicalHeaderKey(name)] = value
+	}
+	for name, value := range base {
+		if strValue, ok := value.(string); ok && strValue != "" {
+			headers[name] = strValue
+		}
+	}
+	return headers
+}
+
+// parseRequestsJSON 解析 flamingo 输出的 requests.json
+func parseRequestsJSON(content []byte) ([]importedRequest, error) {
+	var requests []request
+	if err := json.Unmarshal(content, &requests); err != nil {
+		return nil, err
+	}
+	result := make([]importedRequest, 0, len(requests))
+	for _, req := range requests {
+		data, _ := b64.StdEncoding.DecodeString(req.Data)
+		result = append(result, importedRequest{Method: req.Method, URL: req.URL, Headers: req.Headers, Data: string(data)})
+	}
+	return result, nil
+}
+
+// parseHAR 解析 HAR 文件
+func parseHAR(content []byte) ([]importedRequest, error) {
+	var har harFile
+	if err := json.Unmarshal(content, &har); err != nil {
+		return nil, err
+	}
+	result := make([]importedRequest, 0, len(har.Log.Entries))
+	for _, entry := range har.Log.Entries {
+		item := importedRequest{
+			Method:  entry.Request.Method,
+			URL:     entry.Request.URL,
+			Headers: make(map[string]interface{}),
+		}
+		for _, h := range entry.Request.Headers {
+			item.Headers[h.Name] = h.Value
+		}
+		if entry.Request.PostData != nil {
+			item.Data = entry.Request.PostData.Text
+			if getHeader(item.Headers, "Content-Type") == "" && entry.Request.PostData.MimeType != "" {
+				item.Headers["Content-Type"] = entry.Request.PostData.MimeType
+			}
+		}
+		result = append(result, item)
+	}
+	return result, nil
+}
+
+// parseBurpXML 解析 Burp Suite 导出的 XML 文件
+func parseBurpXML(content []byte) ([]importedRequest, error) {
+	var items burpItems
+	if err := xml.Unmarshal(content, &items); err != nil {
+		return nil, err
+	}
+	result := make([]importedRequest, 0, len(items.Items))
+	for _, it := range items.Items {
+		item := importedRequest{Method: it.Method, URL: it.URL, Headers: make(map[string]interface{})}
+
+		raw := []byte(it.Request.Value)
+		if it.Request.Base64 {
+			decoded, err := b64.StdEncoding.DecodeString(strings.TrimSpace(it.Request.Value))
+			if err != nil {
+				continue
+			}
+			raw = decoded
+		}
+		// 解析原始 HTTP 请求报文中的请求头和请求体
+		if httpReq, err := http.ReadRequest(bufio.NewReader(bytes.NewReader(raw))); err == nil {
+			for name, values := range httpReq.Header {
+				item.Headers[name] = strings.Join(values, ", ")
+			}
+			body, _ := io.ReadAll(httpReq.Body)
+			item.Data = string(body)
+			if item.Method == "" {
+				item.Method = httpReq.Method
+			}
+		}
+		result = append(result, item)
+	}
+	return result, nil
+}
+
+// ImportCredentials 将导入请求中的 Cookie 和 Authorization 加入会话：cookie 作用于请求主机，
+// Authorization 作为请求主机的作用域请求头；会话中已有的同名 cookie 和该主机已有的 Authorization 优先
+func (s *Session) ImportCredentials(rawURL string, headers map[string]interface{}) {
+	host := hostOf(rawURL)
+	if host == "" {
+		return
+	}
+	if cookie := getHeader(headers, "Cookie"); cookie != "" {
+		existing := make(map[string]bool)
+		if u, err := url.Parse(rawURL); err == nil {
+			for _, c := range s.Jar().Cookies(u) {
+				existing[c.Name] = true
+			}
+		}
+		cookies := make([]*network.Cookie, 0)
+		for _, c := range parseCookieHeader(cookie, rawURL) {
+			if !existing[c.Name] {
+				cookies = append(cookies, c)
+			}
+		}
+		s.SetCookies(cookies)
+	}
+	if auth := getHeader(headers, "Authorization"); auth != "" {
+		if _, ok := s.HeadersFor(rawURL)["Authorization"]; !ok {
+			s.mu.Lock()
+			rules := append(append([]HeaderRule{}, s.headerRules...), HeaderRule{Host: host, Name: "Authorization", Value: auth})
+			s.mu.Unlock()
+			s.SetHeaderRules(rules)
+		}
+	}
+}
@@ -0,0 +1,132 @@
+package main
+
+import (
+	b64 "encoding/base64"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestParseHAR(t *testing.T) {
+	har := `{"log": {"entries": [
+		{"request": {"method": "GET", "url": "https://app.example.com/a", "headers": [{"name": "Accept", "value": "text/html"}]}},
+		{"request": {"method": "POST", "url": "https://app.example.com/api", "headers": [],
+			"postData": {"mimeType": "application/json", "text": "{\"id\":1}"}}}
+	]}}`
+	items, err := parseHAR([]byte(har))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []importedRequest{
+		{Method: "GET", URL: "https://app.example.com/a", Headers: map[string]interface{}{"Accept": "text/html"}},
+		{Method: "POST", URL: "https://app.example.com/api", Headers: map[string]interface{}{"Content-Type": "application/json"}, Data: `{"id":1}`},
+	}
+	if !reflect.DeepEqual(items, want) {
+		t.Errorf("parseHAR = %+v, want %+v", items, want)
+	}
+	if _, err := parseHAR([]byte(`{"log":`)); err == nil {
+		t.Error("expected error for truncated HAR")
+	}
+}
+
+func TestParseBurpXML(t *testing.T) {
+	raw := "POST /login HTTP/1.1\r\nHost: app.example.com\r\nContent-Type: application/x-www-form-urlencoded\r\nContent-Length: 7\r\n\r\nuser=me"
+	xml := `<?xml version="1.0"?><items>
+		<item><url>https://app.example.com/login</url><method>POST</method><request base64="true">` + b64.StdEncoding.EncodeToString([]byte(raw)) + `</request></item>
+		<item><url>https://app.example.com/bad</url><method>GET</method><request base64="true">!!!</request></item>
+	</items>`
+	items, err := parseBurpXML([]byte(xml))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(items) != 1 {
+		t.Fatalf("items = %+v, want 1 (invalid base64 skipped)", items)
+	}
+	item := items[0]
+	if item.Method != "POST" || item.URL != "https://app.example.com/login" || item.Data != "user=me" ||
+		getHeader(item.Headers, "Content-Type") != "application/x-www-form-urlencoded" {
+		t.Errorf("item = %+v", item)
+	}
+}
+
+func TestMergeImportHeaders(t *testing.T) {
+	imported := map[string]interface{}{
+		"accept":            "text/html",
+		"Accept-Encoding":   "gzip, br",
+		"If-None-Match":     `"abc"`,
+		"If-Modified-Since": "Mon, 01 Jan 2024 00:00:00 GMT",
+		"Cache-Control":     "no-cache",
+		"Sec-Fetch-Mode":    "navigate",
+		"Sec-CH-UA":         `"Chromium";v="120"`,
+		"Cookie":            "sid=stale",
+		"Authorization":     "Bearer stale",
+		"Content-Type":      "application/json",
+		":authority":        "app.example.com",
+		"Host":              "app.example.com",
+		"User-Agent":        "old",
+	}
+	base := map[string]interface{}{"User-Agent": "flamingo", "Referer": ""}
+
+	got := mergeImportHeaders(imported, base, false)
+	want := map[string]interface{}{"Accept": "text/html", "User-Agent": "flamingo"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("without body = %v, want %v", got, want)
+	}
+	if got := mergeImportHeaders(imported, base, true); got["Content-Type"] != "application/json" {
+		t.Errorf("with body: Content-Type dropped: %v", got)
+	}
+}
+
+func TestImportCredentials(t *testing.T) {
+	session := NewSession()
+	session.SetCookies(parseCookieHeader("sid=fresh", "https://app.example.com/"))
+	session.SetHeaderRules([]HeaderRule{{Host: "api.example.com", Name: "Authorization", Value: "Bearer fresh"}})
+
+	session.ImportCredentials("https://app.example.com/a", map[string]interface{}{"Cookie": "sid=stale; theme=dark", "Authorization": "Basic dXNlcjpwYXNz"})
+	session.ImportCredentials("https://api.example.com/v1", map[string]interface{}{"authorization": "Bearer stale"})
+
+	if got := session.CookieHeader("https://app.example.com/"); got != "sid=fresh; theme=dark" && got != "theme=dark; sid=fresh" {
+		t.Errorf("app cookies = %q", got)
+	}
+	if got := session.CookieHeader("https://cdn.example.net/"); got != "" {
+		t.Errorf("third-party cookies = %q", got)
+	}
+	tests := map[string]string{
+		"https://app.example.com/":    "Basic dXNlcjpwYXNz",
+		"https://api.example.com/":    "Bearer fresh",
+		"https://cdn.example.net/x":   "",
+		"https://sub.app.example.com": "",
+	}
+	for rawURL, want := range tests {
+		if got := session.HeadersFor(rawURL)["Authorization"]; got != want {
+			t.Errorf("Authorization for %s = %q, want %q", rawURL, got, want)
+		}
+	}
+}
+
+func TestImportRequests(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history.har")
+	har := `{"log": {"entries": [{"request": {"method": "get", "url": "https://app.example.com/a",
+		"headers": [{"name": "Cookie", "value": "sid=1"}, {"name": "If-None-Match", "value": "\"x\""}]}}]}}`
+	if err := os.WriteFile(path, []byte(har), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	// 不导入凭据时丢弃 Cookie
+	requests, err := importRequests(path, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(requests) != 1 || requests[0].Method != "GET" || requests[0].Source != "import" || len(requests[0].Headers) != 0 {
+		t.Errorf("requests = %+v", requests)
+	}
+
+	session := NewSession()
+	if _, err := importRequests(path, nil, session); err != nil {
+		t.Fatal(err)
+	}
+	if got := session.CookieHeader("https://app.example.com/"); got != "sid=1" {
+		t.Errorf("session cookies = %q, want sid=1", got)
+	}
+}
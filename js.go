@@ -1,35 +1,122 @@
 package main
 
 const (
-	bypassHeadlessDetectJS = `(function(w, n, wn) {
-		// Pass the Webdriver Test.
-		Object.defineProperty(n, 'webdriver', {
-		  get: () => false,
+	// 隐身脚本，__STEALTH_CONFIG__ 替换为 stealthConfig（JSON），按当前页面主机选择隐身配置
+	stealthJS = `(function(config) {
+		// 主机作用域匹配，与 Go 端 hostMatch 一致
+		const hostMatch = (pattern, host) => {
+			host = host.toLowerCase();
+			if (pattern === '*') return true;
+			if (pattern.startsWith('*.')) {
+				const domain = pattern.slice(2);
+				return host === domain || host.endsWith('.' + domain);
+			}
+			return host === pattern;
+		};
+		let profile = 'off';
+		config.rules.forEach((rule) => {
+			if (hostMatch(rule.host, location.hostname)) profile = rule.profile;
 		});
+		if (profile === 'off') return;
 
-		// Pass the Plugins Length Test.
-		Object.defineProperty(n, 'plugins', {
-		  get: () => [1, 2, 3, 4, 5],
-		});
+		// 被替换的函数在 toString 时表现为原生函数
+		const patched = new WeakSet();
+		const nativeToString = Function.prototype.toString;
+		const toString = function toString() {
+			return patched.has(this) ? 'function ' + this.name + '() { [native code] }' : nativeToString.call(this);
+		};
+		patched.add(toString);
+		Function.prototype.toString = toString;
+		const native = (fn, name) => {
+			Object.defineProperty(fn, 'name', {value: name});
+			patched.add(fn);
+			return fn;
+		};
+		const getter = (proto, prop, value) => {
+			Object.defineProperty(proto, prop, {
+				get: native(function() { return value; }, 'get ' + prop),
+				configurable: true,
+				enumerable: true,
+			});
+		};
 
-		// Pass the Languages Test.
-		Object.defineProperty(n, 'languages', {
-		  get: () => ['zh-CN', 'zh'],
-		});
+		getter(Navigator.prototype, 'webdriver', false);
+		if (profile === 'minimal') return;
+
+		const fp = config.fingerprint;
+		if (fp.languages && fp.languages.length > 0) getter(Navigator.prototype, 'languages', Object.freeze(fp.languages.slice()));
+		if (fp.platform) getter(Navigator.prototype, 'platform', fp.platform);
+		if (fp.device_memory) getter(Navigator.prototype, 'deviceMemory', fp.device_memory);
+
+		// 旧版无头模式没有插件，使用与桌面 Chrome 一致的 PDF 插件列表
+		if (navigator.plugins.length === 0 && !fp.mobile) {
+			const mimeTypes = [
+				{type: 'application/pdf', suffixes: 'pdf', description: 'Portable Document Format'},
+				{type: 'text/pdf', suffixes: 'pdf', description: 'Portable Document Format'},
+			];
+			const names = ['PDF Viewer', 'Chrome PDF Viewer', 'Chromium PDF Viewer', 'Microsoft Edge PDF Viewer', 'WebKit built-in PDF'];
+			const plugins = names.map((name) => {
+				const plugin = Object.create(Plugin.prototype);
+				mimeTypes.forEach((mimeType, i) => { plugin[i] = Object.assign(Object.create(MimeType.prototype), mimeType, {enabledPlugin: plugin}); });
+				Object.defineProperties(plugin, {
+					name: {value: name}, filename: {value: 'internal-pdf-viewer'},
+					description: {value: 'Portable Document Format'}, length: {value: mimeTypes.length},
+				});
+				return plugin;
+			});
+			const pluginArray = Object.create(PluginArray.prototype);
+			plugins.forEach((plugin, i) => { pluginArray[i] = plugin; });
+			Object.defineProperties(pluginArray, {
+				length: {value: plugins.length},
+				item: {value: native(function(i) { return plugins[i] || null; }, 'item')},
+				namedItem: {value: native(function(name) { return plugins.find((p) => p.name === name) || null; }, 'namedItem')},
+				refresh: {value: native(function() {}, 'refresh')},
+			});
+			getter(Navigator.prototype, 'plugins', pluginArray);
+			getter(Navigator.prototype, 'pdfViewerEnabled', true);
+		}
 
-		// Pass the Chrome Test.
-		w.chrome = {
-		  runtime: {},
-		};
+		// chrome 对象
+		if (!window.chrome) {
+			Object.defineProperty(window, 'chrome', {value: {}, writable: true, configurable: true, enumerable: true});
+		}
+		if (!window.chrome.runtime) {
+			window.chrome.runtime = {
+				OnInstalledReason: {CHROME_UPDATE: 'chrome_update', INSTALL: 'install', SHARED_MODULE_UPDATE: 'shared_module_update', UPDATE: 'update'},
+				PlatformOs: {ANDROID: 'android', CROS: 'cros', LINUX: 'linux', MAC: 'mac', OPENBSD: 'openbsd', WIN: 'win'},
+				connect: native(function() { throw new TypeError('Error in invocation of runtime.connect'); }, 'connect'),
+				sendMessage: native(function() { throw new TypeError('Error in invocation of runtime.sendMessage'); }, 'sendMessage'),
+			};
+		}
+		if (!window.chrome.app) {
+			window.chrome.app = {isInstalled: false, InstallState: {DISABLED: 'disabled', INSTALLED: 'installed', NOT_INSTALLED: 'not_installed'}};
+		}
 
-		// Pass the Permissions Test.
-		const originalQuery = wn.permissions.query;
-		return wn.permissions.query = (parameters) => (
-		  parameters.name === 'notifications' ?
-			Promise.resolve({ state: Notification.permission }) :
-			originalQuery(parameters)
-		);
-	})(window, navigator, window.navigator);`
+		// 通知权限：无头模式下 Notification.permission 为 denied，而 permissions.query 返回 prompt
+		if (navigator.permissions && window.Notification) {
+			const originalQuery = Permissions.prototype.query;
+			Permissions.prototype.query = native(function(parameters) {
+				if (parameters && parameters.name === 'notifications') {
+					const state = Notification.permission === 'default' ? 'prompt' : Notification.permission;
+					return Promise.resolve(Object.setPrototypeOf({state: state, onchange: null}, PermissionStatus.prototype));
+				}
+				return originalQuery.call(this, parameters);
+			}, 'query');
+		}
+
+		// WebGL 厂商和渲染器（UNMASKED_VENDOR_WEBGL、UNMASKED_RENDERER_WEBGL），避免暴露 SwiftShader
+		if (fp.webgl_vendor) {
+			[window.WebGLRenderingContext, window.WebGL2RenderingContext].forEach((ctx) => {
+				if (!ctx) return;
+				const originalGetParameter = ctx.prototype.getParameter;
+				ctx.prototype.getParameter = native(function(parameter) {
+					if (parameter === 37445) return fp.webgl_vendor;
+					if (parameter === 37446) return fp.webgl_renderer;
+					return originalGetParameter.call(this, parameter);
+				}, 'getParameter');
+			});
+		}
+	})(__STEALTH_CONFIG__);`
 
 	initHookJS = `
 		// ==================== 常量定义 ====================
@@ -479,9 +566,22 @@ const (
 		iframe.name = IFRAME_NAME;
 		document.body.appendChild(iframe);
 		
+		// 上报表单中的隐藏字段，供参数清单标记
+		function reportHiddenInputs(form) {
+			const names = Array.from(form.elements)
+				.filter(el => el.nodeName === 'INPUT' && el.type === 'hidden' && el.name)
+				.map(el => el.name);
+			if (names.length === 0) return;
+			try {
+				const action = new URL(form.getAttribute('action') || '', document.baseURI).href;
+				window.sendLink(JSON.stringify({url: action, source: 'hidden-input', params: names}));
+			} catch (e) {}
+		}
+
 		// 填充所有表单
 		const forms = Array.from(document.forms);
 		forms.forEach((form) => {
+			reportHiddenInputs(form);
 			form.setAttribute('target', IFRAME_NAME);
 			
 			Array.from(form.elements).forEach((ele) => {
@@ -614,4 +714,101 @@ const (
 			});
 		});
 	})();`
+	// 记录 postMessage 监听器和收到的消息结构，须在页面脚本之前执行
+	postMessageHookJS = `(function() {
+		const MAX_HANDLER = 500;
+		const MAX_SAMPLE = 500;
+		const report = (data) => {
+			try {
+				window.sendLink(JSON.stringify({url: location.href, source: 'postmessage', message: data}));
+			} catch (e) {}
+		};
+		const targetName = (target) => {
+			if (target === window) return 'window';
+			if (typeof Worker !== 'undefined' && target instanceof Worker) return 'worker';
+			if (typeof MessagePort !== 'undefined' && target instanceof MessagePort) return 'port';
+			if (typeof BroadcastChannel !== 'undefined' && target instanceof BroadcastChannel) return 'broadcast';
+			return '';
+		};
+		const reportListener = (target, via, listener) => {
+			const name = targetName(target);
+			if (!name || !listener) return;
+			let handler = '';
+			try {
+				handler = String(typeof listener === 'function' ? listener : listener.handleEvent);
+			} catch (e) {}
+			report({
+				kind: 'listener', target: name, via: via,
+				handler: handler.slice(0, MAX_HANDLER),
+				checks_origin: /\.origin\b|\borigin\s*[!=]==?/.test(handler),
+			});
+		};
+
+		// hook addEventListener('message', ...)
+		const originalAddEventListener = EventTarget.prototype.addEventListener;
+		EventTarget.prototype.addEventListener = function(type, listener, options) {
+			if (type === 'message') reportListener(this, 'addEventListener', listener);
+			return originalAddEventListener.apply(this, arguments);
+		};
+
+		// hook onmessage 赋值
+		[window, typeof Worker !== 'undefined' && Worker.prototype, typeof MessagePort !== 'undefined' && MessagePort.prototype, typeof BroadcastChannel !== 'undefined' && BroadcastChannel.prototype].forEach((proto) => {
+			if (!proto) return;
+			let owner = proto;
+			while (owner && !Object.getOwnPropertyDescriptor(owner, 'onmessage')) owner = Object.getPrototypeOf(owner);
+			const descriptor = owner && Object.getOwnPropertyDescriptor(owner, 'onmessage');
+			if (!descriptor || !descriptor.set || !descriptor.configurable) return;
+			Object.defineProperty(owner, 'onmessage', Object.assign({}, descriptor, {
+				set: function(listener) {
+					reportListener(this, 'onmessage', listener);
+					return descriptor.set.call(this, listener);
+				},
+			}));
+		});
+
+		// 记录收到的跨窗口消息结构，相同来源和结构只上报一次
+		const shapeOf = (value, depth) => {
+			if (value === null) return 'null';
+			if (Array.isArray(value)) return depth > 3 || value.length === 0 ? 'array' : [shapeOf(value[0], depth + 1)];
+			if (typeof value === 'object') {
+				if (depth > 3) return 'object';
+				const shape = {};
+				Object.keys(value).slice(0, 30).forEach((key) => { shape[key] = shapeOf(value[key], depth + 1); });
+				return shape;
+			}
+			return typeof value;
+		};
+		const seenShapes = new Set();
+		originalAddEventListener.call(window, 'message', (event) => {
+			let shape, sample;
+			try {
+				shape = JSON.stringify(shapeOf(event.data, 0));
+				sample = typeof event.data === 'string' ? event.data : JSON.stringify(event.data);
+			} catch (e) {
+				return;
+			}
+			const key = event.origin + '|' + shape;
+			if (seenShapes.has(key) || seenShapes.size >= 20) return;
+			seenShapes.add(key);
+			report({kind: 'message', origin: event.origin, shape: JSON.parse(shape), sample: String(sample).slice(0, MAX_SAMPLE)});
+		}, true);
+	})();`
+	// 收集页面 meta 标签和表单隐藏字段中的反 CSRF 令牌（正则与 csrfNamePattern 保持一致）
+	collectTokensJS = `(function() {
+		const TOKEN_RE = /csrf|xsrf|anti.?forgery|authenticity_token|verificationtoken|nonce|^_token$/i;
+		const tokens = [];
+		document.querySelectorAll('meta[name]').forEach((meta) => {
+			if (meta.content && TOKEN_RE.test(meta.name)) {
+				tokens.push({type: 'meta', name: meta.name, selector: 'meta[name="' + meta.name + '"]'});
+			}
+		});
+		document.querySelectorAll('input[type=hidden][name]').forEach((input) => {
+			if (TOKEN_RE.test(input.name)) {
+				tokens.push({type: 'form', name: input.name, selector: 'input[name="' + input.name + '"]'});
+			}
+		});
+		if (tokens.length > 0) {
+			window.sendLink(JSON.stringify({url: location.href, source: 'csrf-token', tokens: tokens}));
+		}
+	})();`
 )
@@ -0,0 +1,212 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/chromedp/cdproto/network"
+	"github.com/chromedp/cdproto/storage"
+	"github.com/chromedp/chromedp"
+)
+
+// 登录步骤动作
+const (
+	LoginNavigate     = "navigate"      // 导航到 url
+	LoginFill         = "fill"          // 向 selector 填充 value，{{totp}} 替换为当前 TOTP 验证码
+	LoginClick        = "click"         // 点击 selector
+	LoginWaitSelector = "wait_selector" // 等待 selector 可见
+	LoginWaitURL      = "wait_url"      // 等待当前 URL 匹配正则 value
+	LoginSleep        = "sleep"         // 等待 value 时长，如 "2s"
+)
+
+// LoginStep 登录脚本中的单个步骤
+type LoginStep struct {
+	Action   string `json:"action"`
+	URL      string `json:"url,omitempty"`
+	Selector string `json:"selector,omitempty"`
+	Value    string `json:"value,omitempty"`
+}
+
+// LoginScript 声明式登录脚本
+type LoginScript struct {
+	Steps   []LoginStep `json:"steps"`
+	Timeout string      `json:"timeout,omitempty"` // 整个脚本的超时时间，默认 1m
+
+	timeout time.Duration
+	totpKey []byte // TOTP 密钥，由 -totp_secret 指定
+}
+
+// loadLoginScript 加载并校验登录脚本，totpSecret 为 base32 编码的 TOTP 密钥，可为空
+func loadLoginScript(path, totpSecret string) (*LoginScript, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var script LoginScript
+	if err := json.Unmarshal(data, &script); err != nil {
+		return nil, fmt.Errorf("parse login script: %w", err)
+	}
+	if len(script.Steps) == 0 {
+		return nil, errors.New("login script has no steps")
+	}
+
+	script.timeout = time.Minute
+	if script.Timeout != "" {
+		if script.timeout, err = time.ParseDuration(script.Timeout); err != nil {
+			return nil, fmt.Errorf("invalid login timeout: %w", err)
+		}
+	}
+
+	if totpSecret != "" {
+		if script.totpKey, err = decodeTOTPSecret(totpSecret); err != nil {
+			return nil, err
+		}
+	}
+
+	for i, step := range script.Steps {
+		if strings.Contains(step.Value, totpPlaceholder) && script.totpKey == nil {
+			return nil, fmt.Errorf("step %d: %s requires -totp_secret", i+1, totpPlaceholder)
+		}
+		switch step.Action {
+		case LoginNavigate:
+			if err := validateURL(step.URL); err != nil {
+				return nil, fmt.Errorf("step %d: %w", i+1, err)
+			}
+		case LoginFill, LoginClick, LoginWaitSelector:
+			if step.Selector == "" {
+				return nil, fmt.Errorf("step %d: selector is required for %s", i+1, step.Action)
+			}
+		case LoginWaitURL:
+			if _, err := regexp.Compile(step.Value); err != nil {
+				return nil, fmt.Errorf("step %d: %w", i+1, err)
+			}
+		case LoginSleep:
+			if _, err := time.ParseDuration(step.Value); err != nil {
+				return nil, fmt.Errorf("step %d: %w", i+1, err)
+			}
+		default:
+			return nil, fmt.Errorf("step %d: unknown action %q", i+1, step.Action)
+		}
+	}
+	return &script, nil
+}
+
+// waitURL 等待当前页面 URL 匹配正则
+func waitURL(re *regexp.Regexp) chromedp.Action {
+	return chromedp.ActionFunc(func(ctx context.Context) error {
+		ticker := time.NewTicker(200 * time.Millisecond)
+		defer ticker.Stop()
+		for {
+			var location string
+			if err := chromedp.Location(&location).Do(ctx); err == nil && re.MatchString(location) {
+				return nil
+			}
+			select {
+			case <-ticker.C:
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}
+	})
+}
+
+// fillTOTP 在执行时计算验证码并填充，重新登录时同样生效
+func fillTOTP(selector, value string, key []byte) chromedp.Action {
+	return chromedp.ActionFunc(func(ctx context.Context) error {
+		code, wait := currentTOTP(key, time.Now())
+		if wait > 0 {
+			// 当前验证码即将过期，等待下一周期
+			if err := chromedp.Sleep(wait).Do(ctx); err != nil {
+				return err
+			}
+		}
+		return chromedp.SendKeys(selector, strings.ReplaceAll(value, totpPlaceholder, code), chromedp.ByQuery).Do(ctx)
+	})
+}
+
+// stepAction 将登录步骤转换为 chromedp 动作
+func (s *LoginScript) stepAction(step LoginStep) chromedp.Action {
+	switch step.Action {
+	case LoginNavigate:
+		return chromedp.Navigate(step.URL)
+	case LoginFill:
+		var fill chromedp.Action = chromedp.SendKeys(step.Selector, step.Value, chromedp.ByQuery)
+		if strings.Contains(step.Value, totpPlaceholder) {
+			fill = fillTOTP(step.Selector, step.Value, s.totpKey)
+		}
+		return chromedp.Tasks{
+			chromedp.WaitVisible(step.Selector, chromedp.ByQuery),
+			chromedp.SetValue(step.Selector, "", chromedp.ByQuery),
+			fill,
+		}
+	case LoginClick:
+		return chromedp.Click(step.Selector, chromedp.ByQuery)
+	case LoginWaitSelector:
+		return chromedp.WaitVisible(step.Selector, chromedp.ByQuery)
+	case LoginWaitURL:
+		return waitURL(regexp.MustCompile(step.Value))
+	case LoginSleep:
+		d, _ := time.ParseDuration(step.Value)
+		return chromedp.Sleep(d)
+	}
+	return chromedp.Tasks{}
+}
+
+// runLogin 在独立标签页中执行登录脚本，并将产生的 cookie 和 Web Storage 写入会话
+func runLogin(tabCtx context.Context, conf *TabConfig) error {
+	script := conf.LoginScript
+	ctx, cancel := chromedp.NewContext(tabCtx)
+	defer cancel()
+	ctx, cancelTimeout := context.WithTimeout(ctx, script.timeout)
+	defer cancelTimeout()
+
+	actions := []chromedp.Action{
+		enableSessionInterception(ctx, conf),
+		network.SetExtraHTTPHeaders(conf.browserHeaders(conf.Headers)),
+		conf.Emulation.Apply(),
+		conf.Stealth.Apply(conf.Emulation),
+	}
+	for _, step := range script.Steps {
+		actions = append(actions, script.stepAction(step))
+	}
+	if err := chromedp.Run(ctx, actions...); err != nil {
+		return fmt.Errorf("run login script: %w", err)
+	}
+
+	// 收集登录标签页所在浏览器上下文的全部 cookie 和当前源的 Web Storage
+	// 未指定浏览器上下文时返回默认上下文的 cookie，角色和隔离模式使用的独立上下文须显式指定
+	var cookies []*network.Cookie
+	var origin, local, session string
+	if err := chromedp.Run(ctx,
+		chromedp.ActionFunc(func(ctx context.Context) error {
+			getCookies := storage.GetCookies()
+			if id := chromedp.FromContext(ctx).BrowserContextID; id != "" {
+				getCookies = getCookies.WithBrowserContextID(id)
+			}
+			var err error
+			cookies, err = getCookies.Do(ctx)
+			return err
+		}),
+		chromedp.Evaluate(`location.origin`, &origin),
+		chromedp.Evaluate(`JSON.stringify(Object.assign({}, window.localStorage))`, &local),
+		chromedp.Evaluate(`JSON.stringify(Object.assign({}, window.sessionStorage))`, &session),
+	); err != nil {
+		return fmt.Errorf("collect login session: %w", err)
+	}
+
+	localItems := make(map[string]string)
+	sessionItems := make(map[string]string)
+	_ = json.Unmarshal([]byte(local), &localItems)
+	_ = json.Unmarshal([]byte(session), &sessionItems)
+
+	conf.Session.SetCookies(cookies)
+	conf.Session.SetStorage(origin, localItems, sessionItems)
+	GetGlobalLogger().Info(fmt.Sprintf("Login succeeded: %d cookies, %d localStorage items, %d sessionStorage items", len(cookies), len(localItems), len(sessionItems)))
+	return nil
+}
@@ -1,10 +1,12 @@
 package main
 
 import (
+	"context"
 	"errors"
 	"flag"
 	"fmt"
 	"log"
+	"net/url"
 	"os"
 	"os/signal"
 	"strings"
@@ -38,6 +40,18 @@ func processCookie(cookie string) string {
 	return sb.String()
 }
 
+// stringList 可重复指定的字符串参数
+type stringList []string
+
+func (s *stringList) String() string {
+	return strings.Join(*s, ",")
+}
+
+func (s *stringList) Set(value string) error {
+	*s = append(*s, value)
+	return nil
+}
+
 // validateURL 验证 URL 格式
 func validateURL(url string) error {
 	if url == "" || !strings.HasPrefix(url, "http") {
@@ -46,6 +60,22 @@ func validateURL(url string) error {
 	return nil
 }
 
+// validateRemoteDebuggingURL 验证远程调试地址，如 ws://127.0.0.1:9222/ 或 http://127.0.0.1:9222/
+func validateRemoteDebuggingURL(rawURL string) error {
+	if rawURL == "" {
+		return nil
+	}
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Port() == "" {
+		return fmt.Errorf("invalid remote debugging URL %q, expected ws://host:port/ or http://host:port/", rawURL)
+	}
+	switch u.Scheme {
+	case "ws", "wss", "http", "https":
+		return nil
+	}
+	return fmt.Errorf("invalid remote debugging URL %q, expected ws://host:port/ or http://host:port/", rawURL)
+}
+
 // validateChromiumPath 验证 Chromium 路径
 func validateChromiumPath(path string) error {
 	if path != "" {
@@ -57,16 +87,47 @@ func validateChromiumPath(path string) error {
 }
 
 func main() {
-	var url, ua, cookie, chromiumPath, outputPath, logPath, logLevel string
+	// 子命令
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "query":
+			runQuery(os.Args[2:])
+			return
+		case "diff":
+			runDiff(os.Args[2:])
+			return
+		}
+	}
+
+	var sessionOpts SessionOptions
+	var url, ua, chromiumPath, outputPath, paramReportPath, dbPath, logPath, logLevel string
 	flag.StringVar(&url, "url", "", "Initial target URL")
 	flag.StringVar(&ua, "ua", "flamingo", "User-Agent header")
-	flag.StringVar(&cookie, "cookie", "", "HTTP Cookie (e.g. \"PHPSESSID=a8d127e..\")")
+	flag.StringVar(&sessionOpts.Cookie, "cookie", "", "HTTP Cookie (e.g. \"PHPSESSID=a8d127e..\")")
 	tabTimeout := flag.Duration("tab_timeout", 3*time.Minute, "Tab timeout")
 	waitJSExecTime := flag.Duration("wait_js_exec_time", 1*time.Minute, "Wait js exec timeout")
 	crawlTotalTime := flag.Duration("crawl_total_time", 30*time.Minute, "Crawl total time")
 	triggerEventInterval := flag.Int("trigger_event_interval", 5000, "Trigger event interval, unit:ms")
 	mode := flag.Bool("gui", false, "The browser mode, default headless")
 	flag.StringVar(&chromiumPath, "chromium_path", "", "The path of chromium executable file")
+	var remoteDebuggingURL string
+	flag.StringVar(&remoteDebuggingURL, "remote_debugging_url", "", "Attach to a running browser instead of launching one, e.g. ws://127.0.0.1:9222/ or http://127.0.0.1:9222/")
+	var emulationNames, emulationPath string
+	flag.StringVar(&emulationNames, "emulation", "", "Comma-separated emulation profiles to crawl with and merge: desktop, iphone, android-tablet or names from -emulation_file")
+	flag.StringVar(&emulationPath, "emulation_file", "", "The path of custom emulation profiles file (JSON array)")
+	var stealthValues stringList
+	flag.Var(&stealthValues, "stealth", "Stealth profile \"profile\" or \"[host] profile\": chrome (default), minimal or off (repeatable, later wins)")
+	var policyPath string
+	flag.StringVar(&policyPath, "resource_policy", "", "The path of request interception policy file (JSON) that allows, blocks or records requests")
+	var rewritePath string
+	flag.StringVar(&rewritePath, "rewrite_rules", "", "The path of rewrite rules file (JSON) that rewrites headers and URLs, mocks responses or strips response headers")
+	isolation := flag.String("isolation", IsolationShared, "Browser context isolation: shared, role (one incognito context per crawl or role) or tab (one per tab)")
+	isolationSeed := flag.String("isolation_seed", "session", "Seed new tabs with session cookies and Web Storage: session or none")
+	var userDataDir string
+	var extensionPaths, chromeFlags stringList
+	flag.StringVar(&userDataDir, "user_data_dir", "", "The browser profile directory to reuse (consent, logins, certificates)")
+	flag.Var(&extensionPaths, "extension", "The path of unpacked browser extension to load (repeatable)")
+	flag.Var(&chromeFlags, "chrome_flag", "Extra browser flag \"name\", \"name=value\" or \"name=false\" to remove a default flag (repeatable)")
 	flag.StringVar(&outputPath, "output_path", "requests.json", "The path of output json file")
 	tabConcurrentQuantity := flag.Int("tab_concurrent_quantity", 3, "Number of concurrent tab pages")
 	printVer := flag.Bool("version", false, "The version of program")
@@ -79,6 +140,43 @@ func main() {
 	flag.StringVar(&logLevel, "log_level", "info", "Log level: debug, info, warn, error")
 	useSeedUrls := flag.Bool("seed_urls", true, "Fetch seed URLs from robots.txt and sitemap.xml")
 	maxRequests := flag.Int("max_requests", 100000, "Maximum number of requests to store")
+	flag.IntVar(&webSocketSampleLimit, "websocket_samples", 20, "Maximum distinct WebSocket messages sampled per endpoint, 0 to record endpoints only")
+	flag.StringVar(&paramReportPath, "param_report_path", "", "The path of parameter inventory report (.csv for CSV, otherwise JSON)")
+	var postMessageReportPath string
+	flag.StringVar(&postMessageReportPath, "postmessage_report_path", "", "The path of postMessage listeners and message shapes report (JSON)")
+	flag.StringVar(&dbPath, "db_path", "", "The path of SQLite result file, queryable with 'flamingo query'")
+	var importPaths stringList
+	flag.Var(&sessionOpts.Headers, "H", "Custom header \"Name: value\" or \"[host] Name: value\", defaults to the entrance host (repeatable)")
+	flag.StringVar(&sessionOpts.HeaderFile, "header_file", "", "The path of file with one custom header per line, same format as -H")
+	flag.StringVar(&sessionOpts.BearerToken, "bearer_token", "", "Bearer token sent as Authorization header to the entrance host")
+	flag.Var(&sessionOpts.HTTPAuth, "http_auth", "HTTP basic/digest/NTLM credentials \"user:pass\" or \"[host] user:pass\", defaults to the entrance host (repeatable)")
+	flag.StringVar(&sessionOpts.CookieFile, "cookie_file", "", "The path of cookie file to import (Netscape cookies.txt or JSON)")
+	flag.StringVar(&sessionOpts.StorageFile, "storage_file", "", "The path of JSON file with localStorage and sessionStorage items per origin")
+	var loginScriptPath string
+	flag.StringVar(&loginScriptPath, "login_script", "", "The path of declarative login script (JSON), run before crawling")
+	var totpSecret string
+	flag.StringVar(&totpSecret, "totp_secret", "", "Base32 TOTP secret, {{totp}} in login script fill values is replaced with the current code")
+	var monitorConf SessionMonitorConfig
+	flag.StringVar(&monitorConf.LoggedInRegex, "logged_in_regex", "", "Page content regex indicating a logged in session")
+	flag.StringVar(&monitorConf.LoggedOutRegex, "logged_out_regex", "", "Page content regex indicating a logged out session")
+	flag.StringVar(&monitorConf.LoginURLRegex, "login_url_regex", "", "Navigating to a URL matching this regex indicates a logged out session")
+	flag.StringVar(&monitorConf.SessionCookie, "session_cookie", "", "Name of the session cookie whose disappearance indicates a logged out session")
+	var rolesPath, accessMatrixPath string
+	flag.StringVar(&rolesPath, "roles", "", "The path of role profiles file (JSON), crawl as each role and compare access")
+	flag.StringVar(&accessMatrixPath, "access_matrix_path", "access_matrix.json", "The path of access control matrix report in multi-role mode (.csv for CSV, otherwise JSON)")
+	replayUnsafe := flag.Bool("replay_unsafe", false, "Also replay non-GET/HEAD/OPTIONS requests across roles in multi-role mode")
+	var proxyURL, proxyBypass string
+	flag.StringVar(&proxyURL, "proxy", "", "Upstream proxy for browser and Go clients: http://, https:// or socks5://[user:pass@]host:port")
+	flag.StringVar(&proxyBypass, "proxy_bypass", "", "Comma-separated hosts that bypass the proxy: host, *.example.com, .example.com, IP, CIDR or <local>")
+	var resolveValues stringList
+	flag.Var(&resolveValues, "resolve", "Resolve host to IP in browser and Go clients \"host:ip\", host may be *.example.com (repeatable, later wins)")
+	var tlsOpts TLSOptions
+	flag.BoolVar(&tlsOpts.IgnoreCertErrors, "ignore_cert_errors", false, "Ignore TLS certificate errors in browser and Go clients")
+	flag.StringVar(&tlsOpts.CACert, "ca_cert", "", "The path of PEM CA bundle to trust in addition to system roots")
+	flag.StringVar(&tlsOpts.ClientCert, "client_cert", "", "The path of PEM client certificate for mutual TLS")
+	flag.StringVar(&tlsOpts.ClientKey, "client_key", "", "The path of PEM client private key (default: read from -client_cert)")
+	flag.StringVar(&tlsOpts.ClientCertHost, "client_cert_host", "", "Host the browser presents the client certificate to, e.g. *.example.com (default: entrance host)")
+	flag.Var(&importPaths, "import", "Import requests.json, HAR or Burp XML file as seeds (repeatable)")
 	
 	flag.Parse()
 	
@@ -108,9 +206,23 @@ func main() {
 	
 	// 创建请求存储
 	store := NewRequestStore()
+	if dbPath != "" {
+		sqliteStore, err := NewSQLiteStore(dbPath)
+		if err != nil {
+			log.Fatalf("Failed to open sqlite store: %v\n", err)
+		}
+		store.SetBackend(sqliteStore)
+	}
 	
+	// 输出配置
+	outputConf := &OutputConfig{
+		RequestsPath:          outputPath,
+		ParamReportPath:       paramReportPath,
+		PostMessageReportPath: postMessageReportPath,
+	}
+
 	// 优雅关闭处理
-	setupGracefulShutdown(store, outputPath, progressDone)
+	setupGracefulShutdown(store, outputConf, progressDone)
 
 	// 校验、处理程序参数
 	if err := validateURL(url); err != nil {
@@ -120,17 +232,140 @@ func main() {
 	os.Setenv("ENTRANCE_URL", strings.ToLower(url))
 
 	// 处理 cookie
-	cookie = processCookie(cookie)
+	sessionOpts.Cookie = processCookie(sessionOpts.Cookie)
 
 	// 验证 Chromium 路径
 	if err := validateChromiumPath(chromiumPath); err != nil {
 		log.Fatalln(err)
 	}
+	if err := validateRemoteDebuggingURL(remoteDebuggingURL); err != nil {
+		log.Fatalln(err)
+	}
+	if err := validateIsolation(*isolation, *isolationSeed); err != nil {
+		log.Fatalln(err)
+	}
+	extensions, err := resolveExtensionPaths(extensionPaths)
+	if err != nil {
+		log.Fatalln(err)
+	}
+	for _, chromeFlag := range chromeFlags {
+		if _, _, err := parseChromeFlag(chromeFlag); err != nil {
+			log.Fatalln(err)
+		}
+	}
+
+	// 加载登录脚本
+	var loginScript *LoginScript
+	if loginScriptPath != "" {
+		var err error
+		if loginScript, err = loadLoginScript(loginScriptPath, totpSecret); err != nil {
+			log.Fatalln(err)
+		}
+	}
+
+	if *isolationSeed == "none" && loginScript != nil {
+		GetGlobalLogger().Warn("-isolation_seed none is set with -login_script, tabs will not receive the login session")
+	}
+
+	if totpSecret != "" && loginScript == nil {
+		GetGlobalLogger().Warn("-totp_secret is set without -login_script, it will be ignored")
+	}
+
+	// 请求拦截策略
+	var policy *ResourcePolicy
+	if policyPath != "" {
+		if policy, err = loadResourcePolicy(policyPath); err != nil {
+			log.Fatalln(err)
+		}
+	}
+
+	// 请求和响应改写规则
+	if rewritePath != "" {
+		rewriteConf, err := loadRewriteConfig(rewritePath)
+		if err != nil {
+			log.Fatalln(err)
+		}
+		configureRewrite(rewriteConf)
+	}
+
+	// 设备仿真配置，显式指定的 -ua 优先
+	emulationProfiles, err := loadEmulationProfiles(emulationNames, emulationPath)
+	if err != nil {
+		log.Fatalln(err)
+	}
+	uaSet := false
+	flag.Visit(func(f *flag.Flag) {
+		uaSet = uaSet || f.Name == "ua"
+	})
+	if uaSet {
+		for i, profile := range emulationProfiles {
+			emulationProfiles[i] = profile.WithUserAgent(ua)
+		}
+	} else if len(emulationProfiles) > 0 && emulationProfiles[0].UserAgent != "" {
+		// Go 端请求（种子 URL 等）使用第一个仿真配置的 User-Agent
+		ua = emulationProfiles[0].UserAgent
+	}
+
+	// 隐身层，显式指定的 -ua 优先于真实浏览器版本的 User-Agent
+	stealthRules, err := parseStealthRules(stealthValues)
+	if err != nil {
+		log.Fatalln(err)
+	}
+	stealthConf := &StealthConfig{Rules: stealthRules}
+	if uaSet {
+		stealthConf.UserAgent = ua
+	}
+
+	// 会话监控
+	sessionMonitor, err := NewSessionMonitor(monitorConf)
+	if err != nil {
+		log.Fatalln(err)
+	}
+	if sessionMonitor != nil && loginScript == nil {
+		GetGlobalLogger().Warn("Session indicators are set without -login_script, session loss will only be logged")
+	}
+
+	// 上游代理，浏览器和 Go 端请求共用
+	proxyConf, err := parseProxy(proxyURL, proxyBypass)
+	if err != nil {
+		log.Fatalln(err)
+	}
+	if proxyConf.HasCredentials() && proxyConf.URL.Scheme == "socks5" {
+		GetGlobalLogger().Warn("Chromium does not support SOCKS5 proxy authentication, only Go-side requests will authenticate")
+	}
+	configureProxy(proxyConf)
+
+	// 主机解析覆盖，浏览器和 Go 端请求共用，范围检查仍使用原主机名
+	resolveConf, err := parseResolveRules(resolveValues)
+	if err != nil {
+		log.Fatalln(err)
+	}
+	for _, rule := range resolveConf {
+		GetGlobalLogger().Info(fmt.Sprintf("Resolving %s to %s", rule.Host, rule.IP))
+	}
+	if len(resolveConf) > 0 && proxyConf != nil {
+		GetGlobalLogger().Warn("Hosts routed through -proxy are resolved by the proxy, -resolve only applies to bypassed hosts")
+	}
+	configureResolve(resolveConf)
+
+	// TLS 配置，浏览器和 Go 端请求共用
+	tlsConf, err := loadTLSConfig(tlsOpts, hostOf(url))
+	if err != nil {
+		log.Fatalln(err)
+	}
+	configureTLS(tlsConf)
 
 	// 浏览器配置
 	browserConf := &BrowserConfig{
 		Headless:     *mode,
 		ChromiumPath: chromiumPath,
+		Proxy:        proxyConf,
+		TLS:          tlsConf,
+		RemoteURL:    remoteDebuggingURL,
+		UserDataDir:  userDataDir,
+		Extensions:   extensions,
+		ExtraFlags:   chromeFlags,
+		Resolve:      resolveConf,
 	}
 
 	// 标签页配置
@@ -142,49 +377,142 @@ func main() {
 		TabConcurrentQuantity: *tabConcurrentQuantity,
 		Headers: map[string]interface{}{
 			"User-Agent": ua,
-			"Cookie":     cookie,
 		},
+		LoginScript:    loginScript,
+		SessionMonitor: sessionMonitor,
+		Isolation:      *isolation,
+		SeedSession:    *isolationSeed == "session",
+		Policy:         policy,
+		Stealth:        stealthConf,
+	}
+
+	// 导入 cookie、Web Storage、自定义请求头和 HTTP 认证凭据，由浏览器和 Go 端请求共享
+	if tabConf.Session, err = buildSession(sessionOpts, url); err != nil {
+		log.Fatalln(err)
+	}
+	tabConf.HTTPClient = newSessionClient(tabConf.Session)
+
+	// 多角色配置：每个角色使用独立的会话、浏览器上下文和请求存储
+	var roles []*roleCrawl
+	if rolesPath != "" {
+		profiles, err := loadRoleProfiles(rolesPath)
+		if err != nil {
+			log.Fatalln(err)
+		}
+		for _, profile := range profiles {
+			roleConf, err := newRoleConfig(tabConf, sessionOpts, monitorConf, profile, url)
+			if err != nil {
+				log.Fatalln(err)
+			}
+			roleStore := NewRoleStore(store, profile.Name)
+			roleStore.SetSession(roleConf.Session)
+			roles = append(roles, &roleCrawl{Name: profile.Name, Conf: roleConf, Store: roleStore})
+		}
+	} else {
+		store.SetSession(tabConf.Session)
+	}
+
+	progressStats.UpdateField("phase", "Initializing browser")
+	
+	// 初始化浏览器
+	allocCtx, cancel := initBrowser(browserConf)
+	defer cancel()
+
+	// 隐身层以真实浏览器的 User-Agent 覆盖时，未指定 -ua 的请求记录和 Go 端请求使用同一 User-Agent
+	if stealthConf.Enabled() && !uaSet && len(emulationProfiles) == 0 {
+		if browserUA, err := browserUserAgent(allocCtx); err != nil {
+			GetGlobalLogger().Warn(fmt.Sprintf("Failed to get browser User-Agent, using %s: %v", ua, err))
+		} else {
+			tabConf.Headers["User-Agent"] = browserUA
+		}
 	}
 
+	// 添加入口 URL
+	store.SaveRequest(geneRequest("GET", url, tabConf.Headers, "", "entrance"))
+
 	// 获取种子 URLs
 	progressStats.UpdateField("phase", "Fetching seed URLs")
 	if *useSeedUrls {
-		seedUrls := fetchSeedUrls(url, tabConf.Headers)
+		seedUrls := fetchSeedUrls(tabConf.HTTPClient, url, tabConf.Headers)
 		for _, seedURL := range seedUrls {
 			req := geneRequest("GET", seedURL, tabConf.Headers, "", "seed")
 			store.SaveRequest(req)
 		}
 	}
 	
-	// 添加入口 URL
-	store.SaveRequest(geneRequest("GET", url, tabConf.Headers, "", "entrance"))
-	
-	progressStats.UpdateField("phase", "Initializing browser")
-	
-	// 初始化浏览器
-	allocCtx, cancel := initBrowser(browserConf)
-	defer cancel()
+	// 导入已有的请求（非 GET 请求只保存，不导航）；多角色爬取时每个角色使用各自的凭据，不导入请求中的凭据
+	importSession := tabConf.Session
+	if len(roles) > 0 {
+		importSession = nil
+	}
+	for _, importPath := range importPaths {
+		imported, err := importRequests(importPath, tabConf.Headers, importSession)
+		if err != nil {
+			log.Fatalln(err)
+		}
+		count := 0
+		for _, req := range imported {
+			if store.SaveRequest(req) {
+				count++
+			}
+		}
+		GetGlobalLogger().Info(fmt.Sprintf("Imported %d of %d requests from %s", count, len(imported), importPath))
+	}
 	
 	progressStats.UpdateField("phase", "Crawling")
 	progressStats.UpdateField("active", *tabConcurrentQuantity)
 	
-	// 创建标签页，执行爬虫任务
-	crawl(store, allocCtx, tabConf, progressStats)
+	// 创建标签页，执行爬虫任务；所有角色和仿真配置共享 -crawl_total_time 时间上限
+	crawlCtx, crawlCancel := context.WithTimeout(allocCtx, *crawlTotalTime)
+	defer crawlCancel()
+	var accessMatrix *AccessMatrix
+	if len(roles) > 0 {
+		crawlRoles(store, crawlCtx, roles, emulationProfiles, progressStats)
+
+		// 以每个角色的会话回放其它角色发现的请求
+		progressStats.UpdateField("phase", "Replaying across roles")
+		accessMatrix = replayAcrossRoles(allocCtx, roles, *replayUnsafe)
+		if err := outputAccessMatrix(accessMatrix, accessMatrixPath); err != nil {
+			GetGlobalLogger().Error("Failed to save access matrix", err)
+		}
+	} else {
+		crawlProfiles(store, crawlCtx, tabConf, emulationProfiles, progressStats)
+	}
 
 	// 停止进度报告
 	close(progressDone)
 	
 	// 输出 json
 	progressStats.UpdateField("phase", "Saving results")
-	outputRst(store.GetRequests(), outputPath)
+	saveOutputs(store, outputConf)
+	store.CloseBackend()
 	
 	fmt.Printf("\n[+] Crawl completed!\n")
 	fmt.Printf("[+] Total requests collected: %d\n", store.GetRequestCount())
 	fmt.Printf("[+] Output file: %s\n", outputPath)
+	if paramReportPath != "" {
+		fmt.Printf("[+] Param report: %s\n", paramReportPath)
+	}
+	if postMessageReportPath != "" {
+		fmt.Printf("[+] postMessage report: %s\n", postMessageReportPath)
+	}
+	if dbPath != "" {
+		fmt.Printf("[+] SQLite file: %s\n", dbPath)
+	}
+	if accessMatrix != nil {
+		fmt.Printf("[+] Access matrix: %s (%d endpoints)\n", accessMatrixPath, len(accessMatrix.Entries))
+		summary := summarizeAccessMatrix(accessMatrix)
+		for _, role := range accessMatrix.Roles {
+			fmt.Printf("    %s can reach %d endpoints discovered only by other roles\n", role, summary[role])
+		}
+		for _, role := range accessMatrix.Skipped {
+			fmt.Printf("    %s was not crawled (crawl time exhausted), excluded from the matrix\n", role)
+		}
+	}
 }
 
 // setupGracefulShutdown 设置优雅关闭
-func setupGracefulShutdown(store *RequestStore, outputPath string, progressDone chan struct{}) {
+func setupGracefulShutdown(store *RequestStore, outputConf *OutputConfig, progressDone chan struct{}) {
 	s := make(chan os.Signal, 1)
 	signal.Notify(s, syscall.SIGTERM, syscall.SIGHUP, syscall.SIGINT)
 	
@@ -201,8 +529,9 @@ func setupGracefulShutdown(store *RequestStore, outputPath string, progressDone
 		}
 		
 		// 保存当前结果
-		outputRst(store.GetRequests(), outputPath)
-		fmt.Printf("[+] Saved %d requests to %s\n", store.GetRequestCount(), outputPath)
+		saveOutputs(store, outputConf)
+		store.CloseBackend()
+		fmt.Printf("[+] Saved %d requests to %s\n", store.GetRequestCount(), outputConf.RequestsPath)
 		
 		os.Exit(0)
 	}()
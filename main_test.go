@@ -0,0 +1,12 @@
+package main
+
+import (
+	"os"
+	"testing"
+)
+
+func TestMain(m *testing.M) {
+	// RequestStore 只保存与入口 URL 同源的请求
+	os.Setenv("ENTRANCE_URL", "https://app.example.com/")
+	os.Exit(m.Run())
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+)
+
+// postMessage 清单上限
+const (
+	maxPostMessageListeners = 20 // 每个页面最多记录的监听器数
+	maxPostMessageSamples   = 20 // 每个页面最多记录的不同结构的消息数
+)
+
+// PostMessageListener 页面注册的 message 事件监听器
+type PostMessageListener struct {
+	Target       string `json:"target"`        // 注册监听器的对象：window、worker、port 或 broadcast
+	Via          string `json:"via"`           // addEventListener 或 onmessage
+	Handler      string `json:"handler"`       // 监听器源码（截断）
+	ChecksOrigin bool   `json:"checks_origin"` // 监听器源码中是否读取了 event.origin
+}
+
+// PostMessageSample 页面收到的消息
+type PostMessageSample struct {
+	Origin string          `json:"origin"`
+	Shape  json.RawMessage `json:"shape"`  // 消息结构：字段名到类型的映射
+	Sample string          `json:"sample"` // 消息内容（JSON 序列化后截断）
+}
+
+// PostMessageEntry 页面的 postMessage 攻击面
+type PostMessageEntry struct {
+	Page      string                `json:"page"`
+	Listeners []PostMessageListener `json:"listeners"`
+	Messages  []PostMessageSample   `json:"messages"`
+}
+
+// postMessageEvent postMessage Hook 上报的监听器或消息
+type postMessageEvent struct {
+	Kind string `json:"kind"` // listener 或 message
+	PostMessageListener
+	Origin string          `json:"origin"`
+	Shape  json.RawMessage `json:"shape"`
+	Sample string          `json:"sample"`
+}
+
+// RecordPostMessage 记录页面的 postMessage 监听器和消息结构，同一页面中相同的监听器和消息结构只记录一次
+func (rs *RequestStore) RecordPostMessage(page string, ev *postMessageEvent) {
+	if ev == nil {
+		return
+	}
+	if normalizedURL, err := normalizeURL(page); err == nil {
+		page = normalizedURL
+	}
+
+	rs.mu.Lock()
+	if rs.postMessages == nil {
+		rs.postMessages = make(map[string]*PostMessageEntry)
+	}
+	entry, ok := rs.postMessages[page]
+	if !ok {
+		entry = &PostMessageEntry{Page: page, Listeners: []PostMessageListener{}, Messages: []PostMessageSample{}}
+		rs.postMessages[page] = entry
+		rs.postMessagePages = append(rs.postMessagePages, page)
+	}
+	added := false
+	switch ev.Kind {
+	case "listener":
+		added = len(entry.Listeners) < maxPostMessageListeners
+		for _, l := range entry.Listeners {
+			if l.Target == ev.Target && l.Handler == ev.Handler {
+				added = false
+				break
+			}
+		}
+		if added {
+			entry.Listeners = append(entry.Listeners, ev.PostMessageListener)
+		}
+	case "message":
+		added = len(entry.Messages) < maxPostMessageSamples
+		for _, m := range entry.Messages {
+			if m.Origin == ev.Origin && string(m.Shape) == string(ev.Shape) {
+				added = false
+				break
+			}
+		}
+		if added {
+			entry.Messages = append(entry.Messages, PostMessageSample{Origin: ev.Origin, Shape: ev.Shape, Sample: ev.Sample})
+		}
+	}
+	parent := rs.parent
+	rs.mu.Unlock()
+
+	if parent != nil {
+		parent.RecordPostMessage(page, ev)
+		return
+	}
+	if added && ev.Kind == "listener" {
+		GetGlobalLogger().Info(fmt.Sprintf("postMessage listener on %s (target: %s, checks origin: %v)", page, ev.Target, ev.ChecksOrigin))
+	}
+}
+
+// PostMessageInventory 按页面发现顺序返回 postMessage 清单
+func (rs *RequestStore) PostMessageInventory() []PostMessageEntry {
+	rs.mu.RLock()
+	defer rs.mu.RUnlock()
+	entries := make([]PostMessageEntry, 0, len(rs.postMessagePages))
+	for _, page := range rs.postMessagePages {
+		entries = append(entries, *rs.postMessages[page])
+	}
+	return entries
+}
+
+// outputPostMessageReport 输出 postMessage 清单（JSON）
+func outputPostMessageReport(entries []PostMessageEntry, path string) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("create postMessage report: %w", err)
+	}
+	defer file.Close()
+
+	encoder := json.NewEncoder(file)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(entries)
+}
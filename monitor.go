@@ -0,0 +1,245 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"sync"
+	"time"
+
+	"github.com/chromedp/cdproto/cdp"
+	"github.com/chromedp/cdproto/network"
+	"github.com/chromedp/chromedp"
+)
+
+// 最多重新登录次数，避免登出页面导致的无限循环
+const maxRelogins = 20
+
+// 最多保留的待检测请求数，未配置登录脚本时会话丢失后不会重新登录，避免无限增长
+const maxPendingRequests = 1000
+
+// SessionMonitorConfig 会话状态判定条件
+type SessionMonitorConfig struct {
+	LoggedInRegex  string // 页面内容匹配则视为已登录
+	LoggedOutRegex string // 页面内容匹配则视为已登出
+	LoginURLRegex  string // 顶层页面跳转到匹配的 URL 视为已登出
+	SessionCookie  string // 该 cookie 消失视为已登出
+}
+
+// SessionMonitor 会话监控：检测登录状态丢失，暂停爬取并重新登录
+type SessionMonitor struct {
+	gate sync.RWMutex // 导航持有读锁，重新登录持有写锁，以暂停所有标签页
+
+	loggedIn      *regexp.Regexp
+	loggedOut     *regexp.Regexp
+	loginURL      *regexp.Regexp
+	sessionCookie string
+
+	mu         sync.Mutex
+	cookieSeen bool // 会话 cookie 是否出现过
+	lastLogin  time.Time
+	relogins   int
+	requeued   map[string]bool
+	pending    []request // 上次会话检测正常后各标签页访问的请求，会话丢失后重新入队
+	lost       bool      // 已检测到会话丢失，重新登录前不再清空 pending
+}
+
+// NewSessionMonitor 创建会话监控，未配置任何条件时返回 nil
+func NewSessionMonitor(conf SessionMonitorConfig) (*SessionMonitor, error) {
+	if conf.LoggedInRegex == "" && conf.LoggedOutRegex == "" && conf.LoginURLRegex == "" && conf.SessionCookie == "" {
+		return nil, nil
+	}
+
+	m := &SessionMonitor{
+		sessionCookie: conf.SessionCookie,
+		requeued:      make(map[string]bool),
+	}
+	var err error
+	if conf.LoggedInRegex != "" {
+		if m.loggedIn, err = regexp.Compile(conf.LoggedInRegex); err != nil {
+			return nil, fmt.Errorf("invalid logged in regex: %w", err)
+		}
+	}
+	if conf.LoggedOutRegex != "" {
+		if m.loggedOut, err = regexp.Compile(conf.LoggedOutRegex); err != nil {
+			return nil, fmt.Errorf("invalid logged out regex: %w", err)
+		}
+	}
+	if conf.LoginURLRegex != "" {
+		if m.loginURL, err = regexp.Compile(conf.LoginURLRegex); err != nil {
+			return nil, fmt.Errorf("invalid login url regex: %w", err)
+		}
+	}
+	return m, nil
+}
+
+// Acquire 开始导航前调用，重新登录期间阻塞
+func (m *SessionMonitor) Acquire() {
+	if m != nil {
+		m.gate.RLock()
+	}
+}
+
+// Release 导航及会话检测结束后调用
+func (m *SessionMonitor) Release() {
+	if m != nil {
+		m.gate.RUnlock()
+	}
+}
+
+// Guard 在读锁内执行导航及会话检测，fn 中途退出或 panic 时也会释放
+func (m *SessionMonitor) Guard(fn func()) {
+	m.Acquire()
+	defer m.Release()
+	fn()
+}
+
+// IsLoginURL 判断规范化后的 URL 是否为登录页
+func (m *SessionMonitor) IsLoginURL(normalizedURL string) bool {
+	return m != nil && m.loginURL != nil && m.loginURL.MatchString(normalizedURL)
+}
+
+// Check 检测当前标签页的会话是否丢失，并记录该请求：会话正常时清空之前记录的请求，丢失时保留至重新登录
+func (m *SessionMonitor) Check(ctx context.Context, tabState *TabState, req request) bool {
+	if m == nil {
+		return false
+	}
+	lost := m.detectLoss(ctx, tabState, req)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if lost {
+		m.lost = true
+	} else if !m.lost {
+		m.pending = m.pending[:0]
+	}
+	if len(m.pending) < maxPendingRequests {
+		m.pending = append(m.pending, req)
+	}
+	return lost
+}
+
+// detectLoss 按配置的条件检测会话是否丢失
+func (m *SessionMonitor) detectLoss(ctx context.Context, tabState *TabState, req request) bool {
+	if tabState.TakeSessionLost() {
+		return true
+	}
+
+	c := chromedp.FromContext(ctx)
+	targetCtx := cdp.WithExecutor(ctx, c.Target)
+
+	// 页面内容
+	if m.loggedIn != nil || m.loggedOut != nil {
+		var html string
+		if err := chromedp.OuterHTML("html", &html, chromedp.ByQuery).Do(targetCtx); err == nil {
+			if m.loggedOut != nil && m.loggedOut.MatchString(html) {
+				return true
+			}
+			if m.loggedIn != nil && !m.loggedIn.MatchString(html) {
+				return true
+			}
+		}
+	}
+
+	// 会话 cookie
+	if m.sessionCookie != "" {
+		cookies, err := network.GetCookies().WithURLs([]string{req.URL}).Do(targetCtx)
+		if err == nil {
+			found := false
+			for _, cookie := range cookies {
+				if cookie.Name == m.sessionCookie {
+					found = true
+					break
+				}
+			}
+			m.mu.Lock()
+			defer m.mu.Unlock()
+			if found {
+				m.cookieSeen = true
+			} else if m.cookieSeen {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// Relogin 暂停所有标签页并重新执行登录脚本，返回需要重新入队的请求（每个请求只重新入队一次）
+// since 为检测到登出的导航开始时间，若此后已有其它标签页完成重新登录则跳过，
+// 此时当前标签页的请求已由该次重新登录取走
+func (m *SessionMonitor) Relogin(tabCtx context.Context, conf *TabConfig, since time.Time) ([]request, error) {
+	m.gate.Lock()
+	defer m.gate.Unlock()
+
+	m.mu.Lock()
+	if m.lastLogin.After(since) {
+		m.mu.Unlock()
+		return nil, nil
+	}
+	if m.relogins >= maxRelogins {
+		m.mu.Unlock()
+		return nil, fmt.Errorf("max relogins (%d) reached", maxRelogins)
+	}
+	m.relogins++
+	attempt := m.relogins
+	// 持有写锁时所有标签页都已完成会话检测，pending 即登出期间访问的全部请求
+	pending := m.pending
+	m.pending = nil
+	m.lost = false
+	m.mu.Unlock()
+
+	GetGlobalLogger().Warn(fmt.Sprintf("Session lost, re-running login (attempt %d/%d)", attempt, maxRelogins))
+	err := runLogin(tabCtx, conf)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.lastLogin = time.Now()
+	if err != nil {
+		return nil, err
+	}
+	requeue := make([]request, 0, len(pending))
+	for _, req := range pending {
+		key := req.Method + req.URL
+		if !m.requeued[key] {
+			m.requeued[key] = true
+			requeue = append(requeue, req)
+		}
+	}
+	return requeue, nil
+}
+
+// handleSessionLost 重新登录并将登出状态下访问的请求重新入队
+func handleSessionLost(ctx context.Context, num int, req request, conf *TabConfig, reqC chan request, since time.Time) {
+	monitor := conf.SessionMonitor
+	GetGlobalLogger().WarnWithURL(fmt.Sprintf("Tab %d: session lost", num), req.URL)
+
+	if conf.LoginScript == nil {
+		return
+	}
+	requeue, err := monitor.Relogin(ctx, conf, since)
+	if err != nil {
+		GetGlobalLogger().Error("Re-login failed", err)
+		return
+	}
+
+	// 独立浏览器上下文的标签页看不到其它标签页重新登录产生的 cookie，从会话写入
+	if conf.Isolation == IsolationTab && conf.SeedSession {
+		if cookies := conf.Session.CookieParams(); len(cookies) > 0 {
+			if err := chromedp.Run(ctx, network.SetCookies(cookies)); err != nil {
+				GetGlobalLogger().Error(fmt.Sprintf("Tab %d: failed to restore session cookies", num), err)
+			}
+		}
+	}
+
+	if len(requeue) > 0 {
+		GetGlobalLogger().Info(fmt.Sprintf("Tab %d: re-queueing %d requests visited while logged out", num, len(requeue)))
+	}
+	for _, r := range requeue {
+		select {
+		case reqC <- r:
+		default:
+			GetGlobalLogger().WarnWithURL("Queue full, dropping re-queued request", r.URL)
+		}
+	}
+}
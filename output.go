@@ -6,6 +6,32 @@ import (
 	"os"
 )
 
+// OutputConfig 输出配置
+type OutputConfig struct {
+	RequestsPath          string // 请求 JSON 文件路径
+	ParamReportPath       string // 参数清单文件路径，为空则不输出
+	PostMessageReportPath string // postMessage 清单文件路径，为空则不输出
+}
+
+// saveOutputs 输出全部结果文件
+func saveOutputs(store *RequestStore, conf *OutputConfig) {
+	// 爬取过程中 cookie 可能已被轮换，输出最新值
+	store.RefreshSessionHeaders()
+	outputRst(store.GetRequests(), conf.RequestsPath)
+
+	if conf.ParamReportPath != "" {
+		if err := outputParamReport(store.ParamInventory(), conf.ParamReportPath); err != nil {
+			GetGlobalLogger().Error("Failed to write param report", err)
+		}
+	}
+
+	if conf.PostMessageReportPath != "" {
+		if err := outputPostMessageReport(store.PostMessageInventory(), conf.PostMessageReportPath); err != nil {
+			GetGlobalLogger().Error("Failed to write postMessage report", err)
+		}
+	}
+}
+
 func outputRst(requests []request, filepath string) {
 	file, err := os.Create(filepath)
 	if err != nil {
@@ -0,0 +1,143 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"mime"
+	"net/url"
+	"os"
+	"path"
+	"regexp"
+	"strings"
+)
+
+// 请求拦截策略动作
+const (
+	PolicyAllow  = "allow"  // 放行（覆盖默认丢弃的静态资源类型）
+	PolicyBlock  = "block"  // 阻断，不记录
+	PolicyRecord = "record" // 阻断并记录，输出中标记为 blocked
+)
+
+// PolicyRule 请求拦截规则，各条件同时满足才匹配，未设置的条件不参与匹配
+type PolicyRule struct {
+	Action        string   `json:"action"`
+	ResourceTypes []string `json:"resource_types"` // CDP 资源类型，如 Image、Font、Other、XHR
+	URLRegex      string   `json:"url_regex"`
+	MIMETypes     []string `json:"mime_types"` // 根据 URL 扩展名推断的 MIME 类型，支持 "image/*"
+	urlRegex      *regexp.Regexp
+}
+
+// PolicyTarget 按主机覆盖的规则，优先于全局规则
+type PolicyTarget struct {
+	Host  string        `json:"host"` // 主机，格式同 HeaderRule.Host
+	Rules []*PolicyRule `json:"rules"`
+}
+
+// ResourcePolicy 请求拦截策略，按顺序匹配，第一条匹配的规则生效，均未匹配时使用默认策略
+type ResourcePolicy struct {
+	Targets []*PolicyTarget `json:"targets"`
+	Rules   []*PolicyRule   `json:"rules"`
+}
+
+// loadResourcePolicy 加载请求拦截策略文件（JSON）
+func loadResourcePolicy(path string) (*ResourcePolicy, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var policy ResourcePolicy
+	if err := json.Unmarshal(content, &policy); err != nil {
+		return nil, fmt.Errorf("parse %s: %w", path, err)
+	}
+	rules := append([]*PolicyRule{}, policy.Rules...)
+	for _, target := range policy.Targets {
+		if target.Host == "" {
+			return nil, fmt.Errorf("%s: policy target without host", path)
+		}
+		target.Host = strings.ToLower(target.Host)
+		rules = append(rules, target.Rules...)
+	}
+	for i, rule := range rules {
+		switch rule.Action {
+		case PolicyAllow, PolicyBlock, PolicyRecord:
+		default:
+			return nil, fmt.Errorf("%s: rule %d: invalid action %q, expected allow, block or record", path, i+1, rule.Action)
+		}
+		if rule.URLRegex != "" {
+			if rule.urlRegex, err = regexp.Compile(rule.URLRegex); err != nil {
+				return nil, fmt.Errorf("%s: rule %d: invalid url_regex: %w", path, i+1, err)
+			}
+		}
+	}
+	return &policy, nil
+}
+
+// guessMIMEType 根据 URL 扩展名推断 MIME 类型，请求阶段尚无响应，无法获取实际类型
+func guessMIMEType(u *url.URL) string {
+	mimeType, _, _ := strings.Cut(mime.TypeByExtension(strings.ToLower(path.Ext(u.Path))), ";")
+	return strings.TrimSpace(mimeType)
+}
+
+// match 判断规则是否匹配请求
+func (r *PolicyRule) match(resourceType string, u *url.URL) bool {
+	if len(r.ResourceTypes) > 0 {
+		found := false
+		for _, t := range r.ResourceTypes {
+			if strings.EqualFold(t, resourceType) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	if r.urlRegex != nil && !r.urlRegex.MatchString(u.String()) {
+		return false
+	}
+	if len(r.MIMETypes) > 0 {
+		mimeType := guessMIMEType(u)
+		if mimeType == "" {
+			return false
+		}
+		found := false
+		for _, pattern := range r.MIMETypes {
+			pattern = strings.ToLower(pattern)
+			if pattern == mimeType || (strings.HasSuffix(pattern, "/*") && strings.HasPrefix(mimeType, strings.TrimSuffix(pattern, "*"))) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// Match 获取请求对应的策略动作，未匹配任何规则时返回 false
+func (p *ResourcePolicy) Match(resourceType, rawURL string) (string, bool) {
+	if p == nil {
+		return "", false
+	}
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return "", false
+	}
+	for _, target := range p.Targets {
+		if !hostMatch(target.Host, u.Hostname()) {
+			continue
+		}
+		for _, rule := range target.Rules {
+			if rule.match(resourceType, u) {
+				return rule.Action, true
+			}
+		}
+	}
+	for _, rule := range p.Rules {
+		if rule.match(resourceType, u) {
+			return rule.Action, true
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestResourcePolicyMatch(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "policy.json")
+	content := `{
+		"targets": [
+			{"host": "*.cdn.example.com", "rules": [{"action": "allow", "mime_types": ["image/*"]}]}
+		],
+		"rules": [
+			{"action": "record", "url_regex": "/analytics/"},
+			{"action": "block", "resource_types": ["Image", "Font"]},
+			{"action": "allow", "resource_types": ["Media"], "mime_types": ["video/mp4"]}
+		]
+	}`
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	policy, err := loadResourcePolicy(path)
+	if err != nil {
+		t.Fatalf("loadResourcePolicy() error = %v", err)
+	}
+
+	tests := []struct {
+		resourceType string
+		url          string
+		wantAction   string
+		wantMatch    bool
+	}{
+		{"Image", "https://img.cdn.example.com/a.png", PolicyAllow, true},
+		{"Image", "https://cdn.example.com/a.PNG", PolicyAllow, true},
+		{"Image", "https://example.com/a.png", PolicyBlock, true},
+		{"font", "https://example.com/a.woff2", PolicyBlock, true},
+		{"XHR", "https://example.com/analytics/collect", PolicyRecord, true},
+		{"Media", "https://example.com/v.mp4", PolicyAllow, true},
+		{"Media", "https://example.com/v.webm", "", false},
+		{"Document", "https://example.com/", "", false},
+	}
+	for _, tt := range tests {
+		action, ok := policy.Match(tt.resourceType, tt.url)
+		if action != tt.wantAction || ok != tt.wantMatch {
+			t.Errorf("Match(%s, %s) = %q, %v, want %q, %v", tt.resourceType, tt.url, action, ok, tt.wantAction, tt.wantMatch)
+		}
+	}
+
+	var nilPolicy *ResourcePolicy
+	if _, ok := nilPolicy.Match("Image", "https://example.com/a.png"); ok {
+		t.Error("nil policy matched")
+	}
+}
+
+func TestLoadResourcePolicyInvalid(t *testing.T) {
+	for _, content := range []string{
+		`{"rules": [{"action": "drop"}]}`,
+		`{"rules": [{"action": "block", "url_regex": "("}]}`,
+		`{"targets": [{"rules": []}]}`,
+	} {
+		path := filepath.Join(t.TempDir(), "policy.json")
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := loadResourcePolicy(path); err == nil {
+			t.Errorf("loadResourcePolicy(%s) error = nil, want error", content)
+		}
+	}
+}
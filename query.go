@@ -0,0 +1,146 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"text/tabwriter"
+)
+
+// queryRow 查询结果行
+type queryRow struct {
+	Method      string   `json:"method"`
+	URL         string   `json:"url"`
+	Pattern     string   `json:"pattern"`
+	ContentType string   `json:"content_type,omitempty"`
+	BodyKind    string   `json:"body_kind,omitempty"`
+	HasFile     bool     `json:"has_file"`
+	Source      string   `json:"source"`
+	Sources     []string `json:"sources"`
+	Status      int64    `json:"status,omitempty"`
+}
+
+// 预置的常用过滤条件
+var queryPresets = map[string]string{
+	"uploads":     "r.method = 'POST' AND r.has_file = 1",
+	"json-posts":  "r.method = 'POST' AND r.body_kind = 'json'",
+	"xml-posts":   "r.method = 'POST' AND r.body_kind = 'xml'",
+	"forms":       "r.body_kind IN ('form', 'multipart')",
+	"no-response": "NOT EXISTS (SELECT 1 FROM responses p WHERE p.url = r.url)",
+}
+
+// runQuery 执行 query 子命令
+func runQuery(args []string) {
+	fs := flag.NewFlagSet("query", flag.ExitOnError)
+	dbPath := fs.String("db", "", "The path of SQLite result file")
+	preset := fs.String("filter", "", "Preset filter: uploads, json-posts, xml-posts, forms, no-response")
+	method := fs.String("method", "", "Filter by HTTP method")
+	source := fs.String("source", "", "Filter by discovery source (any recorded source)")
+	urlLike := fs.String("url", "", "Filter by URL substring")
+	hasFile := fs.Bool("has_file", false, "Only requests with file uploads")
+	format := fs.String("format", "text", "Output format: text, json")
+	limit := fs.Int("limit", 0, "Maximum number of rows, 0 means unlimited")
+	_ = fs.Parse(args)
+
+	if *dbPath == "" {
+		log.Fatalln("-db is required")
+	}
+	if _, err := os.Stat(*dbPath); err != nil {
+		log.Fatalln(err)
+	}
+
+	var conds []string
+	var params []interface{}
+	if *preset != "" {
+		cond, ok := queryPresets[*preset]
+		if !ok {
+			log.Fatalf("unknown filter: %s\n", *preset)
+		}
+		conds = append(conds, cond)
+	}
+	if *method != "" {
+		conds = append(conds, "r.method = ?")
+		params = append(params, strings.ToUpper(*method))
+	}
+	if *source != "" {
+		conds = append(conds, "EXISTS (SELECT 1 FROM sources s WHERE s.request_id = r.id AND s.source = ?)")
+		params = append(params, *source)
+	}
+	if *urlLike != "" {
+		conds = append(conds, "r.url LIKE ?")
+		params = append(params, "%"+*urlLike+"%")
+	}
+	if *hasFile {
+		conds = append(conds, "r.has_file = 1")
+	}
+
+	query := `SELECT r.method, r.url, r.pattern, COALESCE(r.content_type, ''), COALESCE(r.body_kind, ''), r.has_file, r.source,
+		COALESCE((SELECT GROUP_CONCAT(s.source) FROM sources s WHERE s.request_id = r.id), ''),
+		COALESCE((SELECT p.status FROM responses p WHERE p.url = r.url ORDER BY p.id DESC LIMIT 1), 0)
+		FROM requests r`
+	if len(conds) > 0 {
+		query += " WHERE " + strings.Join(conds, " AND ")
+	}
+	query += " ORDER BY r.id"
+	if *limit > 0 {
+		query += fmt.Sprintf(" LIMIT %d", *limit)
+	}
+
+	db, err := sql.Open("sqlite", *dbPath)
+	if err != nil {
+		log.Fatalln(err)
+	}
+	defer db.Close()
+
+	rows, err := db.Query(query, params...)
+	if err != nil {
+		log.Fatalln(err)
+	}
+	defer rows.Close()
+
+	result := make([]queryRow, 0)
+	for rows.Next() {
+		var row queryRow
+		var sources string
+		if err := rows.Scan(&row.Method, &row.URL, &row.Pattern, &row.ContentType, &row.BodyKind, &row.HasFile, &row.Source, &sources, &row.Status); err != nil {
+			log.Fatalln(err)
+		}
+		if sources != "" {
+			row.Sources = strings.Split(sources, ",")
+		}
+		result = append(result, row)
+	}
+	if err := rows.Err(); err != nil {
+		log.Fatalln(err)
+	}
+
+	if *format == "json" {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		_ = encoder.Encode(result)
+		return
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "METHOD\tSTATUS\tBODY\tSOURCES\tURL")
+	for _, row := range result {
+		status := "-"
+		if row.Status != 0 {
+			status = fmt.Sprint(row.Status)
+		}
+		bodyKind := row.BodyKind
+		if row.HasFile {
+			bodyKind += "+file"
+		}
+		if bodyKind == "" {
+			bodyKind = "-"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", row.Method, status, bodyKind, strings.Join(row.Sources, ","), row.URL)
+	}
+	w.Flush()
+	fmt.Printf("\n[+] %d requests\n", len(result))
+}
@@ -0,0 +1,362 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// 参数位置
+const (
+	ParamInQuery  = "query"
+	ParamInForm   = "form"
+	ParamInJSON   = "json"
+	ParamInXML    = "xml"
+	ParamInPath   = "path"
+	ParamInCookie = "cookie"
+	ParamInHeader = "header"
+)
+
+// 每个参数最多保留的样例值数量及长度
+const (
+	maxParamSamples     = 5
+	maxParamSampleBytes = 100
+)
+
+// ParamEntry 参数清单条目
+type ParamEntry struct {
+	Name      string   `json:"name"`
+	Location  string   `json:"location"`
+	Hidden    bool     `json:"hidden"`
+	Types     []string `json:"types"`
+	Samples   []string `json:"samples"`
+	Endpoints []string `json:"endpoints"`
+}
+
+// 浏览器自带的标准请求头，不作为应用参数统计
+var standardHeaders = map[string]bool{
+	"accept":                    true,
+	"accept-encoding":           true,
+	"accept-language":           true,
+	"cache-control":             true,
+	"connection":                true,
+	"content-length":            true,
+	"content-type":              true,
+	"cookie":                    true,
+	"dnt":                       true,
+	"host":                      true,
+	"origin":                    true,
+	"pragma":                    true,
+	"referer":                   true,
+	"upgrade-insecure-requests": true,
+	"user-agent":                true,
+}
+
+// 路径中疑似参数的片段
+var (
+	pathIntRe  = regexp.MustCompile(`^\d+$`)
+	pathUUIDRe = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+	pathHashRe = regexp.MustCompile(`^[0-9a-fA-F]{16,}$`)
+	emailRe    = regexp.MustCompile(`^[^@\s]+@[^@\s]+\.[^@\s]+$`)
+)
+
+// pathSegmentPlaceholder 判断路径片段是否为参数，返回占位符
+func pathSegmentPlaceholder(segment string) string {
+	switch {
+	case pathIntRe.MatchString(segment):
+		return "{id}"
+	case pathUUIDRe.MatchString(segment):
+		return "{uuid}"
+	case pathHashRe.MatchString(segment):
+		return "{hash}"
+	}
+	return ""
+}
+
+// urlPattern 将 URL 归一化为端点模式，如：https://a.com/users/{id}
+func urlPattern(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return rawURL
+	}
+	segments := strings.Split(u.Path, "/")
+	for i, segment := range segments {
+		if placeholder := pathSegmentPlaceholder(segment); placeholder != "" {
+			segments[i] = placeholder
+		}
+	}
+	return u.Scheme + "://" + u.Host + strings.Join(segments, "/")
+}
+
+// getHeader 不区分大小写地读取请求头
+func getHeader(headers map[string]interface{}, name string) string {
+	for key, value := range headers {
+		if strings.EqualFold(key, name) {
+			if strValue, ok := value.(string); ok {
+				return strValue
+			}
+		}
+	}
+	return ""
+}
+
+// inferValueType 推断参数值类型
+func inferValueType(value string) string {
+	if value == "" {
+		return "empty"
+	}
+	if _, err := strconv.ParseInt(value, 10, 64); err == nil {
+		return "integer"
+	}
+	if _, err := strconv.ParseFloat(value, 64); err == nil {
+		return "float"
+	}
+	lower := strings.ToLower(value)
+	switch {
+	case lower == "true" || lower == "false":
+		return "boolean"
+	case pathUUIDRe.MatchString(value):
+		return "uuid"
+	case emailRe.MatchString(value):
+		return "email"
+	case strings.HasPrefix(lower, "http://") || strings.HasPrefix(lower, "https://"):
+		return "url"
+	case json.Valid([]byte(value)) && (strings.HasPrefix(value, "{") || strings.HasPrefix(value, "[")):
+		return "json"
+	}
+	return "string"
+}
+
+// jsonValueType 返回 JSON 值的类型
+func jsonValueType(value interface{}) string {
+	switch value.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case float64:
+		return "number"
+	case string:
+		return "string"
+	case []interface{}:
+		return "array"
+	default:
+		return "object"
+	}
+}
+
+// flattenJSON 将 JSON 树展开为 "a.b[].c" 形式的参数
+func flattenJSON(prefix string, value interface{}, fn func(name, sample, typ string)) {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, child := range v {
+			name := key
+			if prefix != "" {
+				name = prefix + "." + key
+			}
+			flattenJSON(name, child, fn)
+		}
+	case []interface{}:
+		if len(v) == 0 && prefix != "" {
+			fn(prefix, "[]", "array")
+		}
+		for _, item := range v {
+			flattenJSON(prefix+"[]", item, fn)
+		}
+	default:
+		if prefix == "" {
+			return
+		}
+		sample, _ := json.Marshal(v)
+		if s, ok := v.(string); ok {
+			sample = []byte(s)
+		}
+		fn(prefix, string(sample), jsonValueType(v))
+	}
+}
+
+// truncateUTF8 截断字符串到最多 n 字节，不拆分 UTF-8 字符
+func truncateUTF8(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	return s[:n]
+}
+
+// paramInventory 参数清单构建器
+type paramInventory struct {
+	entries map[string]*ParamEntry // key: location + name
+	types   map[string]map[string]bool
+	samples map[string]map[string]bool
+	ends    map[string]map[string]bool
+}
+
+// newParamInventory 创建参数清单构建器
+func newParamInventory() *paramInventory {
+	return &paramInventory{
+		entries: make(map[string]*ParamEntry),
+		types:   make(map[string]map[string]bool),
+		samples: make(map[string]map[string]bool),
+		ends:    make(map[string]map[string]bool),
+	}
+}
+
+// add 记录一次参数出现
+func (pi *paramInventory) add(name, location, sample, typ, endpoint string) {
+	key := location + "\x00" + name
+	entry, ok := pi.entries[key]
+	if !ok {
+		entry = &ParamEntry{Name: name, Location: location}
+		pi.entries[key] = entry
+		pi.types[key] = make(map[string]bool)
+		pi.samples[key] = make(map[string]bool)
+		pi.ends[key] = make(map[string]bool)
+	}
+
+	if !pi.types[key][typ] {
+		pi.types[key][typ] = true
+		entry.Types = append(entry.Types, typ)
+	}
+	sample = truncateUTF8(sample, maxParamSampleBytes)
+	if len(entry.Samples) < maxParamSamples && !pi.samples[key][sample] {
+		pi.samples[key][sample] = true
+		entry.Samples = append(entry.Samples, sample)
+	}
+	if !pi.ends[key][endpoint] {
+		pi.ends[key][endpoint] = true
+		entry.Endpoints = append(entry.Endpoints, endpoint)
+	}
+}
+
+// addRequest 提取单个请求中的全部参数
+func (pi *paramInventory) addRequest(req request) {
+	u, err := url.Parse(req.URL)
+	if err != nil {
+		return
+	}
+	endpoint := req.Method + " " + urlPattern(req.URL)
+
+	// 查询参数
+	for name, values := range u.Query() {
+		for _, value := range values {
+			pi.add(name, ParamInQuery, value, inferValueType(value), endpoint)
+		}
+	}
+
+	// 路径参数
+	for _, segment := range strings.Split(u.Path, "/") {
+		if placeholder := pathSegmentPlaceholder(segment); placeholder != "" {
+			pi.add(placeholder, ParamInPath, segment, inferValueType(segment), endpoint)
+		}
+	}
+
+	// Cookie
+	for _, pair := range strings.Split(getHeader(req.Headers, "Cookie"), ";") {
+		name, value, found := strings.Cut(strings.TrimSpace(pair), "=")
+		if found && name != "" {
+			pi.add(name, ParamInCookie, value, inferValueType(value), endpoint)
+		}
+	}
+
+	// 非标准请求头
+	for name, value := range req.Headers {
+		lower := strings.ToLower(name)
+		if standardHeaders[lower] || strings.HasPrefix(lower, "sec-") || strings.HasPrefix(lower, ":") {
+			continue
+		}
+		if strValue, ok := value.(string); ok {
+			pi.add(name, ParamInHeader, strValue, inferValueType(strValue), endpoint)
+		}
+	}
+
+	// 请求体
+	if req.Body == nil {
+		return
+	}
+	location := ParamInForm
+	switch req.Body.Kind {
+	case BodyJSON:
+		location = ParamInJSON
+	case BodyXML:
+		location = ParamInXML
+	}
+	for _, param := range req.Body.Params {
+		pi.add(param.Name, location, param.Value, param.Type, endpoint)
+	}
+}
+
+// result 输出排序后的参数清单
+func (pi *paramInventory) result(hidden map[string]bool) []ParamEntry {
+	entries := make([]ParamEntry, 0, len(pi.entries))
+	for _, entry := range pi.entries {
+		// 隐藏字段只会出现在表单提交的查询参数或请求体中，且只标记提交到该表单 action 端点的参数
+		if entry.Location == ParamInQuery || entry.Location == ParamInForm {
+			for _, endpoint := range entry.Endpoints {
+				_, pattern, _ := strings.Cut(endpoint, " ")
+				if hidden[pattern+"\x00"+entry.Name] {
+					entry.Hidden = true
+					break
+				}
+			}
+		}
+		sort.Strings(entry.Endpoints)
+		entries = append(entries, *entry)
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].Name != entries[j].Name {
+			return entries[i].Name < entries[j].Name
+		}
+		return entries[i].Location < entries[j].Location
+	})
+	return entries
+}
+
+// ParamInventory 基于已收集的请求生成参数清单
+func (rs *RequestStore) ParamInventory() []ParamEntry {
+	pi := newParamInventory()
+	for _, req := range rs.GetRequests() {
+		pi.addRequest(req)
+	}
+	return pi.result(rs.GetHiddenParams())
+}
+
+// outputParamReport 输出参数清单，扩展名为 .csv 时输出 CSV，否则输出 JSON
+func outputParamReport(entries []ParamEntry, path string) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("create param report: %w", err)
+	}
+	defer file.Close()
+
+	if strings.ToLower(filepath.Ext(path)) != ".csv" {
+		encoder := json.NewEncoder(file)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(entries)
+	}
+
+	writer := csv.NewWriter(file)
+	_ = writer.Write([]string{"name", "location", "hidden", "types", "samples", "endpoints"})
+	for _, entry := range entries {
+		_ = writer.Write([]string{
+			entry.Name,
+			entry.Location,
+			strconv.FormatBool(entry.Hidden),
+			strings.Join(entry.Types, "|"),
+			strings.Join(entry.Samples, "|"),
+			strings.Join(entry.Endpoints, "|"),
+		})
+	}
+	writer.Flush()
+	return writer.Error()
+}
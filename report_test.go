@@ -0,0 +1,158 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestURLPattern(t *testing.T) {
+	tests := map[string]string{
+		"https://app.example.com/users/42/posts":                                 "https://app.example.com/users/{id}/posts",
+		"https://app.example.com/items/3f2504e0-4f89-11d3-9a0c-0305e82c3301?x=1": "https://app.example.com/items/{uuid}",
+		"https://app.example.com/blob/0123456789abcdef0123":                      "https://app.example.com/blob/{hash}",
+		"https://app.example.com/about":                                          "https://app.example.com/about",
+	}
+	for rawURL, want := range tests {
+		if got := urlPattern(rawURL); got != want {
+			t.Errorf("urlPattern(%q) = %q, want %q", rawURL, got, want)
+		}
+	}
+}
+
+func TestInferValueType(t *testing.T) {
+	tests := map[string]string{
+		"":                                     "empty",
+		"42":                                   "integer",
+		"4.2":                                  "float",
+		"TRUE":                                 "boolean",
+		"3f2504e0-4f89-11d3-9a0c-0305e82c3301": "uuid",
+		"a@b.com":                              "email",
+		"https://example.com/":                 "url",
+		`{"a":1}`:                              "json",
+		"hello":                                "string",
+	}
+	for value, want := range tests {
+		if got := inferValueType(value); got != want {
+			t.Errorf("inferValueType(%q) = %q, want %q", value, got, want)
+		}
+	}
+}
+
+func TestParamInventory(t *testing.T) {
+	store := NewRequestStore()
+	headers := map[string]interface{}{
+		"Cookie":         "sid=abc; theme=dark",
+		"X-Api-Key":      "k1",
+		"Content-Type":   "application/json",
+		"Sec-Fetch-Mode": "cors",
+	}
+	store.SaveRequest(geneRequest("GET", "https://app.example.com/users/1?page=2", headers, "", "dom"))
+	store.SaveRequest(geneRequest("GET", "https://app.example.com/users/2?page=x", headers, "", "dom"))
+	store.SaveRequest(geneRequest("POST", "https://app.example.com/api/profile", headers, `{"user":{"name":"中文","age":3}}`, "xhr"))
+	formHeaders := map[string]interface{}{"Content-Type": "application/x-www-form-urlencoded"}
+	store.SaveRequest(geneRequest("POST", "https://app.example.com/login", formHeaders, "csrf=t0k&user=a", "form"))
+	store.MarkHiddenParams("https://app.example.com/login", []string{"csrf"})
+
+	entries := store.ParamInventory()
+	byKey := make(map[string]ParamEntry)
+	for _, entry := range entries {
+		byKey[entry.Location+":"+entry.Name] = entry
+	}
+	wantKeys := []string{
+		"query:page", "path:{id}", "cookie:sid", "cookie:theme", "header:X-Api-Key",
+		"json:user.name", "json:user.age", "form:csrf", "form:user",
+	}
+	if len(byKey) != len(wantKeys) {
+		t.Errorf("entries = %+v, want keys %v", entries, wantKeys)
+	}
+	for _, key := range wantKeys {
+		if _, ok := byKey[key]; !ok {
+			t.Errorf("missing entry %s", key)
+		}
+	}
+
+	page := byKey["query:page"]
+	if !reflect.DeepEqual(page.Types, []string{"integer", "string"}) || !reflect.DeepEqual(page.Samples, []string{"2", "x"}) {
+		t.Errorf("page entry = %+v", page)
+	}
+	if !reflect.DeepEqual(page.Endpoints, []string{"GET https://app.example.com/users/{id}"}) {
+		t.Errorf("page endpoints = %v", page.Endpoints)
+	}
+	if !byKey["form:csrf"].Hidden || byKey["form:user"].Hidden {
+		t.Errorf("hidden flags: csrf = %v, user = %v", byKey["form:csrf"].Hidden, byKey["form:user"].Hidden)
+	}
+	if got := byKey["json:user.age"].Types; !reflect.DeepEqual(got, []string{"number"}) {
+		t.Errorf("json age types = %v", got)
+	}
+	for i := 1; i < len(entries); i++ {
+		if entries[i-1].Name > entries[i].Name {
+			t.Errorf("entries not sorted: %s before %s", entries[i-1].Name, entries[i].Name)
+		}
+	}
+}
+
+func TestParamInventorySampleLimits(t *testing.T) {
+	pi := newParamInventory()
+	for _, value := range []string{"a", "b", "a", "c", "d", "e", "f"} {
+		pi.add("q", ParamInQuery, value, "string", "GET https://app.example.com/")
+	}
+	pi.add("long", ParamInQuery, strings.Repeat("中", maxParamSampleBytes), "string", "GET https://app.example.com/")
+	entries := pi.result(nil)
+	if got := entries[1].Samples; !reflect.DeepEqual(got, []string{"a", "b", "c", "d", "e"}) {
+		t.Errorf("samples = %v", got)
+	}
+	if got := entries[0].Samples[0]; len(got) > maxParamSampleBytes || !strings.HasPrefix(strings.Repeat("中", maxParamSampleBytes), got) {
+		t.Errorf("long sample = %q (%d bytes)", got, len(got))
+	}
+}
+
+func TestOutputParamReport(t *testing.T) {
+	entries := []ParamEntry{{
+		Name:      "id",
+		Location:  ParamInQuery,
+		Hidden:    true,
+		Types:     []string{"integer", "string"},
+		Samples:   []string{"1", "a,b"},
+		Endpoints: []string{"GET https://app.example.com/a", "GET https://app.example.com/b"},
+	}}
+	dir := t.TempDir()
+
+	jsonPath := filepath.Join(dir, "params.json")
+	if err := outputParamReport(entries, jsonPath); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(jsonPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded []ParamEntry
+	if err := json.Unmarshal(data, &decoded); err != nil || !reflect.DeepEqual(decoded, entries) {
+		t.Errorf("JSON report = %s (%v)", data, err)
+	}
+
+	csvPath := filepath.Join(dir, "params.CSV")
+	if err := outputParamReport(entries, csvPath); err != nil {
+		t.Fatal(err)
+	}
+	file, err := os.Open(csvPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	records, err := csv.NewReader(file).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := [][]string{
+		{"name", "location", "hidden", "types", "samples", "endpoints"},
+		{"id", "query", "true", "integer|string", "1|a,b", "GET https://app.example.com/a|GET https://app.example.com/b"},
+	}
+	if !reflect.DeepEqual(records, want) {
+		t.Errorf("CSV report = %v, want %v", records, want)
+	}
+}
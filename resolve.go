@@ -0,0 +1,81 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+)
+
+// ResolveRule 主机到 IP 的解析覆盖规则
+type ResolveRule struct {
+	Host string // 主机：精确主机名、"*.example.com"（该域及其子域）或 "*"
+	IP   net.IP
+}
+
+// resolveRules 包级别的解析覆盖规则，后定义的优先
+var resolveRules []ResolveRule
+
+// parseResolveRules 解析 -resolve 参数 "host:ip"，IPv6 地址可带方括号，如 "app.example.com:[::1]"
+func parseResolveRules(values []string) ([]ResolveRule, error) {
+	rules := make([]ResolveRule, 0, len(values))
+	for _, value := range values {
+		host, addr, found := strings.Cut(strings.TrimSpace(value), ":")
+		host = strings.ToLower(strings.TrimSpace(host))
+		ip := net.ParseIP(strings.Trim(strings.TrimSpace(addr), "[]"))
+		if !found || host == "" || ip == nil {
+			return nil, fmt.Errorf("invalid resolve %q, expected \"host:ip\"", value)
+		}
+		rules = append(rules, ResolveRule{Host: host, IP: ip})
+	}
+	return rules, nil
+}
+
+// lookupOverride 获取主机对应的覆盖 IP，多条规则匹配时使用最后一条
+func lookupOverride(host string) (net.IP, bool) {
+	for i := len(resolveRules) - 1; i >= 0; i-- {
+		if hostMatch(resolveRules[i].Host, host) {
+			return resolveRules[i].IP, true
+		}
+	}
+	return nil, false
+}
+
+// hostResolverRulesFlag 转换为浏览器的 host-resolver-rules 参数，浏览器使用第一条匹配的规则，因此按相反顺序输出
+func hostResolverRulesFlag(rules []ResolveRule) string {
+	mappings := make([]string, 0, len(rules))
+	for i := len(rules) - 1; i >= 0; i-- {
+		ip := rules[i].IP.String()
+		if rules[i].IP.To4() == nil {
+			ip = "[" + ip + "]"
+		}
+		mappings = append(mappings, fmt.Sprintf("MAP %s %s", rules[i].Host, ip))
+		// 浏览器的 "*.example.com" 不匹配 example.com 本身，与 hostMatch 保持一致
+		if strings.HasPrefix(rules[i].Host, "*.") {
+			mappings = append(mappings, fmt.Sprintf("MAP %s %s", rules[i].Host[2:], ip))
+		}
+	}
+	return strings.Join(mappings, ", ")
+}
+
+// overrideDialer 按解析覆盖规则拨号，请求 URL、Host 头和 TLS SNI 仍使用原主机名
+func overrideDialer(dialer *net.Dialer) func(ctx context.Context, network, addr string) (net.Conn, error) {
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		host, port, err := net.SplitHostPort(addr)
+		if err == nil {
+			if ip, ok := lookupOverride(host); ok {
+				addr = net.JoinHostPort(ip.String(), port)
+			}
+		}
+		return dialer.DialContext(ctx, network, addr)
+	}
+}
+
+// configureResolve 设置解析覆盖规则，Go 端所有 HTTP 客户端共享 baseTransport，须在发出请求前调用
+func configureResolve(rules []ResolveRule) {
+	resolveRules = rules
+	if len(rules) > 0 {
+		baseTransport.DialContext = overrideDialer(&net.Dialer{Timeout: 30 * time.Second, KeepAlive: 30 * time.Second})
+	}
+}
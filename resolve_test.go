@@ -0,0 +1,76 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestParseResolveRules(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    string
+		wantErr bool
+	}{
+		{"App.Example.com:10.0.0.5", "app.example.com 10.0.0.5", false},
+		{"*.example.com: 10.0.0.6 ", "*.example.com 10.0.0.6", false},
+		{"v6.example.com:[::1]", "v6.example.com ::1", false},
+		{"v6.example.com:::1", "v6.example.com ::1", false},
+		{"example.com", "", true},
+		{":10.0.0.5", "", true},
+		{"example.com:not-an-ip", "", true},
+	}
+	for _, tt := range tests {
+		rules, err := parseResolveRules([]string{tt.value})
+		if (err != nil) != tt.wantErr {
+			t.Errorf("parseResolveRules(%q) error = %v, wantErr %v", tt.value, err, tt.wantErr)
+			continue
+		}
+		if err == nil {
+			if got := rules[0].Host + " " + rules[0].IP.String(); got != tt.want {
+				t.Errorf("parseResolveRules(%q) = %s, want %s", tt.value, got, tt.want)
+			}
+		}
+	}
+}
+
+func TestHostResolverRulesFlag(t *testing.T) {
+	rules, err := parseResolveRules([]string{"*.example.com:10.0.0.1", "api.example.com:10.0.0.2", "v6.test:[2001:db8::1]"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 浏览器使用第一条匹配的规则，后定义的规则排在前面；通配规则同时映射域名本身
+	want := "MAP v6.test [2001:db8::1], MAP api.example.com 10.0.0.2, MAP *.example.com 10.0.0.1, MAP example.com 10.0.0.1"
+	if got := hostResolverRulesFlag(rules); got != want {
+		t.Errorf("hostResolverRulesFlag() = %q, want %q", got, want)
+	}
+	if got := hostResolverRulesFlag(nil); got != "" {
+		t.Errorf("hostResolverRulesFlag(nil) = %q, want empty", got)
+	}
+}
+
+func TestLookupOverride(t *testing.T) {
+	saved := resolveRules
+	defer func() { resolveRules = saved }()
+	var err error
+	if resolveRules, err = parseResolveRules([]string{"*.example.com:10.0.0.1", "api.example.com:10.0.0.2"}); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		host string
+		want string
+	}{
+		{"api.example.com", "10.0.0.2"},
+		{"www.example.com", "10.0.0.1"},
+		{"example.com", "10.0.0.1"},
+		{"example.org", ""},
+	}
+	for _, tt := range tests {
+		ip, ok := lookupOverride(tt.host)
+		got := ""
+		if ok {
+			got = ip.String()
+		}
+		if got != tt.want {
+			t.Errorf("lookupOverride(%s) = %q, want %q", tt.host, got, tt.want)
+		}
+	}
+}
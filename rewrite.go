@@ -0,0 +1,291 @@
+package main
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/chromedp/cdproto/fetch"
+)
+
+// MockResponse 模拟响应，请求不再发送到服务器
+type MockResponse struct {
+	Status  int64             `json:"status"` // 默认 200
+	Headers map[string]string `json:"headers"`
+	File    string            `json:"file"` // 响应体文件，相对路径以规则文件所在目录为基准
+	Body    string            `json:"body"` // 响应体文本，设置 file 时忽略
+	body    []byte
+}
+
+// RewriteRule 请求和响应改写规则，各条件同时满足才匹配，未设置的条件不参与匹配
+type RewriteRule struct {
+	Name                 string            `json:"name"`           // 规则名，用于日志
+	Host                 string            `json:"host"`           // 主机，格式同 HeaderRule.Host，默认所有主机
+	URLRegex             string            `json:"url_regex"`      // 匹配请求 URL 的正则
+	Methods              []string          `json:"methods"`        // 请求方法
+	ResourceTypes        []string          `json:"resource_types"` // CDP 资源类型，如 Document、XHR、Script
+	SetHeaders           map[string]string `json:"set_headers"`    // 添加或覆盖请求头
+	RemoveHeaders        []string          `json:"remove_headers"` // 删除请求头
+	RewriteURL           string            `json:"rewrite_url"`    // 替换 url_regex 匹配的部分，支持 $1 等分组引用；未设置 url_regex 时替换整个 URL
+	Mock                 *MockResponse     `json:"mock"`
+	StripResponseHeaders []string          `json:"strip_response_headers"` // 删除响应头，如 Content-Security-Policy、X-Frame-Options
+	urlRegex             *regexp.Regexp
+}
+
+// RewriteConfig 改写规则，按顺序对请求应用所有匹配的规则
+type RewriteConfig struct {
+	Rules []*RewriteRule `json:"rules"`
+}
+
+// rewriteConfig 包级别的改写规则，未设置时为 nil
+var rewriteConfig *RewriteConfig
+
+// pendingStrips 等待响应阶段删除响应头的请求：RequestID -> 匹配的规则
+// 请求 URL 可能已被改写，因此在请求阶段记录匹配结果
+var pendingStrips sync.Map
+
+// loadRewriteConfig 加载改写规则文件（JSON）
+func loadRewriteConfig(path string) (*RewriteConfig, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var config RewriteConfig
+	if err := json.Unmarshal(content, &config); err != nil {
+		return nil, fmt.Errorf("parse %s: %w", path, err)
+	}
+	for i, rule := range config.Rules {
+		if rule.Name == "" {
+			rule.Name = fmt.Sprintf("#%d", i+1)
+		}
+		rule.Host = strings.ToLower(rule.Host)
+		if rule.Host == "" {
+			rule.Host = "*"
+		}
+		if rule.URLRegex != "" {
+			if rule.urlRegex, err = regexp.Compile(rule.URLRegex); err != nil {
+				return nil, fmt.Errorf("%s: rule %s: invalid url_regex: %w", path, rule.Name, err)
+			}
+		}
+		if rule.Mock != nil {
+			if rule.Mock.Status == 0 {
+				rule.Mock.Status = http.StatusOK
+			}
+			rule.Mock.body = []byte(rule.Mock.Body)
+			if rule.Mock.File != "" {
+				file := rule.Mock.File
+				if !filepath.IsAbs(file) {
+					file = filepath.Join(filepath.Dir(path), file)
+				}
+				if rule.Mock.body, err = os.ReadFile(file); err != nil {
+					return nil, fmt.Errorf("%s: rule %s: %w", path, rule.Name, err)
+				}
+				if _, ok := headerValue(rule.Mock.Headers, "Content-Type"); !ok {
+					if mimeType := mime.TypeByExtension(filepath.Ext(file)); mimeType != "" {
+						if rule.Mock.Headers == nil {
+							rule.Mock.Headers = make(map[string]string)
+						}
+						rule.Mock.Headers["Content-Type"] = mimeType
+					}
+				}
+			}
+		}
+	}
+	return &config, nil
+}
+
+// configureRewrite 设置改写规则，须在爬取开始前调用
+func configureRewrite(c *RewriteConfig) {
+	rewriteConfig = c
+}
+
+// headerValue 不区分大小写获取请求头
+func headerValue(headers map[string]string, name string) (string, bool) {
+	for key, value := range headers {
+		if strings.EqualFold(key, name) {
+			return value, true
+		}
+	}
+	return "", false
+}
+
+// match 判断规则是否匹配请求
+func (r *RewriteRule) match(method, resourceType, rawURL string) bool {
+	if !hostMatch(r.Host, hostOf(rawURL)) {
+		return false
+	}
+	if r.urlRegex != nil && !r.urlRegex.MatchString(rawURL) {
+		return false
+	}
+	if len(r.Methods) > 0 && !containsFold(r.Methods, method) {
+		return false
+	}
+	if len(r.ResourceTypes) > 0 && !containsFold(r.ResourceTypes, resourceType) {
+		return false
+	}
+	return true
+}
+
+// containsFold 不区分大小写判断列表是否包含指定值
+func containsFold(values []string, value string) bool {
+	for _, v := range values {
+		if strings.EqualFold(v, value) {
+			return true
+		}
+	}
+	return false
+}
+
+// mockRule 获取第一条匹配请求的模拟响应规则，未匹配时返回 nil
+func (c *RewriteConfig) mockRule(ev *fetch.EventRequestPaused) *RewriteRule {
+	if c == nil {
+		return nil
+	}
+	for _, rule := range c.Rules {
+		if rule.Mock != nil && rule.match(ev.Request.Method, ev.ResourceType.String(), ev.Request.URL) {
+			return rule
+		}
+	}
+	return nil
+}
+
+// hasMock 判断请求是否匹配模拟响应规则；模拟响应的请求不会发出，不受拦截策略、静态资源丢弃和登出过滤的影响
+func (c *RewriteConfig) hasMock(ev *fetch.EventRequestPaused) bool {
+	return c.mockRule(ev) != nil
+}
+
+// rewriteRequest 对被拦截的请求应用所有匹配的规则，返回改写后的事件副本（未改写时返回原事件）
+// 命中模拟响应时不应用其它规则；strip 为需要在响应阶段删除响应头的规则
+func (c *RewriteConfig) rewriteRequest(ev *fetch.EventRequestPaused) (result *fetch.EventRequestPaused, mock *MockResponse, rewritten bool, strip []*RewriteRule) {
+	if c == nil {
+		return ev, nil, false, nil
+	}
+	originalURL := ev.Request.URL
+	method := ev.Request.Method
+	resourceType := ev.ResourceType.String()
+
+	if rule := c.mockRule(ev); rule != nil {
+		GetGlobalLogger().Info(fmt.Sprintf("Rewrite rule %s: %s %s: mock %d", rule.Name, method, originalURL, rule.Mock.Status))
+		return ev, rule.Mock, false, nil
+	}
+
+	req := *ev.Request
+	for _, rule := range c.Rules {
+		if !rule.match(method, resourceType, originalURL) {
+			continue
+		}
+		applied := make([]string, 0)
+		if rule.RewriteURL != "" {
+			newURL := rule.RewriteURL
+			if rule.urlRegex != nil {
+				newURL = rule.urlRegex.ReplaceAllString(req.URL, rule.RewriteURL)
+			}
+			if u, err := url.Parse(newURL); err == nil && u.IsAbs() && newURL != req.URL {
+				applied = append(applied, "url -> "+newURL)
+				req.URL = newURL
+			}
+		}
+		if len(rule.RemoveHeaders) > 0 || len(rule.SetHeaders) > 0 {
+			headers := make(map[string]interface{}, len(req.Headers)+len(rule.SetHeaders))
+			removed := make([]string, 0)
+			for name, value := range req.Headers {
+				if containsFold(rule.RemoveHeaders, name) {
+					removed = append(removed, name)
+					continue
+				}
+				headers[name] = value
+			}
+			for name, value := range rule.SetHeaders {
+				for key := range headers {
+					if strings.EqualFold(key, name) {
+						delete(headers, key)
+					}
+				}
+				headers[name] = value
+			}
+			req.Headers = headers
+			if len(removed) > 0 {
+				sort.Strings(removed)
+				applied = append(applied, "remove headers "+strings.Join(removed, ", "))
+			}
+			if len(rule.SetHeaders) > 0 {
+				names := make([]string, 0, len(rule.SetHeaders))
+				for name := range rule.SetHeaders {
+					names = append(names, name)
+				}
+				sort.Strings(names)
+				applied = append(applied, "set headers "+strings.Join(names, ", "))
+			}
+		}
+		if len(rule.StripResponseHeaders) > 0 {
+			strip = append(strip, rule)
+		}
+		if len(applied) > 0 {
+			rewritten = true
+			GetGlobalLogger().Info(fmt.Sprintf("Rewrite rule %s: %s %s: %s", rule.Name, method, originalURL, strings.Join(applied, "; ")))
+		}
+	}
+	if !rewritten {
+		return ev, nil, false, strip
+	}
+	copied := *ev
+	copied.Request = &req
+	return &copied, nil, true, strip
+}
+
+// fulfill 以模拟响应完成请求
+func (m *MockResponse) fulfill(ctx context.Context, requestID fetch.RequestID) error {
+	headers := make([]*fetch.HeaderEntry, 0, len(m.Headers))
+	for name, value := range m.Headers {
+		headers = append(headers, &fetch.HeaderEntry{Name: name, Value: value})
+	}
+	return fetch.FulfillRequest(requestID, m.Status).
+		WithResponseHeaders(headers).
+		WithBody(base64.StdEncoding.EncodeToString(m.body)).
+		Do(ctx)
+}
+
+// isResponseStage 判断是否为响应阶段的拦截事件
+func isResponseStage(ev *fetch.EventRequestPaused) bool {
+	return ev.ResponseStatusCode != 0 || ev.ResponseErrorReason != ""
+}
+
+// continueResponse 放行响应阶段被拦截的请求，删除请求阶段匹配的规则指定的响应头，并清除请求的认证质询记录
+func continueResponse(ctx context.Context, ev *fetch.EventRequestPaused) error {
+	clearAuthAttempts(ev.RequestID)
+	value, ok := pendingStrips.LoadAndDelete(ev.RequestID)
+	if !ok || ev.ResponseErrorReason != "" {
+		return fetch.ContinueRequest(ev.RequestID).Do(ctx)
+	}
+	rules := value.([]*RewriteRule)
+	headers := ev.ResponseHeaders
+	for _, rule := range rules {
+		kept := make([]*fetch.HeaderEntry, 0, len(headers))
+		removed := make([]string, 0)
+		for _, header := range headers {
+			if containsFold(rule.StripResponseHeaders, header.Name) {
+				removed = append(removed, header.Name)
+				continue
+			}
+			kept = append(kept, header)
+		}
+		if len(removed) > 0 {
+			GetGlobalLogger().Info(fmt.Sprintf("Rewrite rule %s: %s %s: strip response headers %s", rule.Name, ev.Request.Method, ev.Request.URL, strings.Join(removed, ", ")))
+		}
+		headers = kept
+	}
+	if len(headers) == len(ev.ResponseHeaders) {
+		return fetch.ContinueResponse(ev.RequestID).Do(ctx)
+	}
+	return fetch.ContinueResponse(ev.RequestID).WithResponseHeaders(headers).Do(ctx)
+}
@@ -0,0 +1,141 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"regexp"
+	"sort"
+	"testing"
+
+	"github.com/chromedp/cdproto/cdp"
+	"github.com/chromedp/cdproto/fetch"
+	"github.com/chromedp/cdproto/network"
+)
+
+// recordingExecutor 记录发送的 CDP 命令
+type recordingExecutor struct {
+	method string
+	params json.RawMessage
+}
+
+func (e *recordingExecutor) Execute(_ context.Context, method string, params, _ any) error {
+	e.method = method
+	e.params, _ = json.Marshal(params)
+	return nil
+}
+
+func pausedEvent(method, rawURL, resourceType string, headers network.Headers) *fetch.EventRequestPaused {
+	return &fetch.EventRequestPaused{
+		RequestID:    "interception-1",
+		Request:      &network.Request{Method: method, URL: rawURL, Headers: headers},
+		ResourceType: network.ResourceType(resourceType),
+	}
+}
+
+func TestRewriteRequest(t *testing.T) {
+	config := &RewriteConfig{Rules: []*RewriteRule{
+		{Name: "api-v2", Host: "*", urlRegex: regexp.MustCompile(`/api/v1/`), RewriteURL: "/api/v2/"},
+		{Name: "headers", Host: "app.example.com", Methods: []string{"get"}, RemoveHeaders: []string{"x-debug"}, SetHeaders: map[string]string{"x-tenant": "b"}},
+		{Name: "second", Host: "*", SetHeaders: map[string]string{"X-Order": "2"}, StripResponseHeaders: []string{"Content-Security-Policy"}},
+		{Name: "post-only", Host: "*", Methods: []string{"POST"}, SetHeaders: map[string]string{"X-Post": "1"}},
+	}}
+	ev := pausedEvent("GET", "https://app.example.com/api/v1/users", "XHR", network.Headers{"X-Debug": "1", "X-Tenant": "a", "Accept": "*/*"})
+
+	result, mock, rewritten, strip := config.rewriteRequest(ev)
+	if mock != nil || !rewritten {
+		t.Fatalf("mock = %v, rewritten = %v", mock, rewritten)
+	}
+	if result.Request.URL != "https://app.example.com/api/v2/users" {
+		t.Errorf("URL = %s", result.Request.URL)
+	}
+	// 请求头名不区分大小写，set_headers 覆盖原有的同名请求头
+	want := network.Headers{"Accept": "*/*", "x-tenant": "b", "X-Order": "2"}
+	if !reflect.DeepEqual(result.Request.Headers, want) {
+		t.Errorf("Headers = %v, want %v", result.Request.Headers, want)
+	}
+	if len(strip) != 1 || strip[0].Name != "second" {
+		t.Errorf("strip = %v", strip)
+	}
+	// 原事件不被修改
+	if ev.Request.URL != "https://app.example.com/api/v1/users" || len(ev.Request.Headers) != 3 {
+		t.Errorf("original event modified: %+v", ev.Request)
+	}
+
+	other := pausedEvent("GET", "https://cdn.example.net/app.js", "Script", nil)
+	// 其它主机只应用 host 为 * 的规则
+	if result, _, _, _ := config.rewriteRequest(other); result.Request.URL != other.Request.URL || !reflect.DeepEqual(result.Request.Headers, network.Headers{"X-Order": "2"}) {
+		t.Errorf("cdn: %+v", result.Request)
+	}
+	var nilConfig *RewriteConfig
+	if result, mock, rewritten, strip := nilConfig.rewriteRequest(ev); result != ev || mock != nil || rewritten || strip != nil {
+		t.Error("nil config changed the request")
+	}
+}
+
+func TestRewriteRequestMock(t *testing.T) {
+	flags := &MockResponse{Status: 200, body: []byte(`{}`)}
+	config := &RewriteConfig{Rules: []*RewriteRule{
+		{Name: "rewrite", Host: "*", SetHeaders: map[string]string{"X-Rewritten": "1"}},
+		{Name: "flags", Host: "*", urlRegex: regexp.MustCompile(`/flags$`), Mock: flags},
+		{Name: "later", Host: "*", urlRegex: regexp.MustCompile(`/flags$`), Mock: &MockResponse{Status: 500}},
+		{Name: "image", Host: "*", ResourceTypes: []string{"image"}, Mock: &MockResponse{Status: 204}},
+	}}
+
+	ev := pausedEvent("GET", "https://app.example.com/flags", "Fetch", nil)
+	result, mock, rewritten, strip := config.rewriteRequest(ev)
+	// 第一条匹配的模拟响应生效，排在前面的改写不应用
+	if mock != flags || rewritten || result != ev || strip != nil {
+		t.Errorf("mock = %v, rewritten = %v", mock, rewritten)
+	}
+	if !config.hasMock(pausedEvent("GET", "https://app.example.com/logo.png", "Image", nil)) {
+		t.Error("image mock not matched")
+	}
+	if config.hasMock(pausedEvent("GET", "https://app.example.com/", "Document", nil)) {
+		t.Error("document matched a mock")
+	}
+}
+
+func TestContinueResponse(t *testing.T) {
+	rule := &RewriteRule{Name: "csp", StripResponseHeaders: []string{"content-security-policy", "X-Frame-Options"}}
+	ev := pausedEvent("GET", "https://app.example.com/", "Document", nil)
+	ev.ResponseStatusCode = 200
+	ev.ResponseHeaders = []*fetch.HeaderEntry{
+		{Name: "Content-Type", Value: "text/html"},
+		{Name: "Content-Security-Policy", Value: "default-src 'self'"},
+		{Name: "x-frame-options", Value: "DENY"},
+	}
+
+	executor := &recordingExecutor{}
+	ctx := cdp.WithExecutor(context.Background(), executor)
+	pendingStrips.Store(ev.RequestID, []*RewriteRule{rule})
+	if err := continueResponse(ctx, ev); err != nil {
+		t.Fatal(err)
+	}
+	if executor.method != fetch.CommandContinueResponse {
+		t.Fatalf("method = %s", executor.method)
+	}
+	var params fetch.ContinueResponseParams
+	if err := json.Unmarshal(executor.params, &params); err != nil {
+		t.Fatal(err)
+	}
+	names := make([]string, 0)
+	for _, header := range params.ResponseHeaders {
+		names = append(names, header.Name)
+	}
+	sort.Strings(names)
+	if !reflect.DeepEqual(names, []string{"Content-Type"}) {
+		t.Errorf("headers = %v", names)
+	}
+	if _, ok := pendingStrips.Load(ev.RequestID); ok {
+		t.Error("pending strip not removed")
+	}
+
+	// 没有等待删除的响应头时直接放行
+	if err := continueResponse(ctx, ev); err != nil {
+		t.Fatal(err)
+	}
+	if executor.method != fetch.CommandContinueRequest {
+		t.Errorf("method = %s, want %s", executor.method, fetch.CommandContinueRequest)
+	}
+}
@@ -0,0 +1,451 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	b64 "encoding/base64"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode"
+
+	"github.com/chromedp/chromedp"
+)
+
+// 回放时读取的最大响应体长度
+const maxReplayBodySize = 2 << 20
+
+// 回放并发数
+const replayConcurrency = 10
+
+// 判定为“可访问”的最低相似度
+const accessSimilarityThreshold = 0.9
+
+// RoleProfile 角色配置：名称、认证来源和可选的登录脚本
+type RoleProfile struct {
+	Name string `json:"name"`
+	SessionOptions
+	LoginScript string `json:"login_script,omitempty"`
+	TOTPSecret  string `json:"totp_secret,omitempty"`
+}
+
+// roleProfiles 角色配置文件结构
+type roleProfiles struct {
+	Roles []RoleProfile `json:"roles"`
+}
+
+// loadRoleProfiles 加载角色配置文件，文件中的相对路径相对于配置文件所在目录
+func loadRoleProfiles(path string) ([]RoleProfile, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var profiles roleProfiles
+	if err := json.Unmarshal(data, &profiles); err != nil {
+		return nil, fmt.Errorf("parse %s: %w", path, err)
+	}
+	if len(profiles.Roles) < 2 {
+		return nil, errors.New("at least two roles are required")
+	}
+
+	dir := filepath.Dir(path)
+	resolve := func(p string) string {
+		if p == "" || filepath.IsAbs(p) {
+			return p
+		}
+		return filepath.Join(dir, p)
+	}
+	names := make(map[string]bool)
+	for i := range profiles.Roles {
+		role := &profiles.Roles[i]
+		if role.Name == "" || names[role.Name] {
+			return nil, fmt.Errorf("role %d: name is empty or duplicated", i+1)
+		}
+		names[role.Name] = true
+		role.CookieFile = resolve(role.CookieFile)
+		role.StorageFile = resolve(role.StorageFile)
+		role.HeaderFile = resolve(role.HeaderFile)
+		role.LoginScript = resolve(role.LoginScript)
+	}
+	return profiles.Roles, nil
+}
+
+// mergeSessionOptions 合并全局和角色的认证来源，列表追加，非空字符串以角色为准
+func mergeSessionOptions(base, role SessionOptions) SessionOptions {
+	merged := base
+	merged.Headers = append(append(stringList{}, base.Headers...), role.Headers...)
+	merged.HTTPAuth = append(append(stringList{}, base.HTTPAuth...), role.HTTPAuth...)
+	if role.Cookie != "" {
+		merged.Cookie = processCookie(role.Cookie)
+	}
+	if role.CookieFile != "" {
+		merged.CookieFile = role.CookieFile
+	}
+	if role.StorageFile != "" {
+		merged.StorageFile = role.StorageFile
+	}
+	if role.HeaderFile != "" {
+		merged.HeaderFile = role.HeaderFile
+	}
+	if role.BearerToken != "" {
+		merged.BearerToken = role.BearerToken
+	}
+	return merged
+}
+
+// newRoleConfig 基于全局标签页配置创建角色配置
+func newRoleConfig(base *TabConfig, baseOpts SessionOptions, monitorConf SessionMonitorConfig, profile RoleProfile, entranceURL string) (*TabConfig, error) {
+	conf := *base
+	conf.Role = profile.Name
+
+	var err error
+	if conf.Session, err = buildSession(mergeSessionOptions(baseOpts, profile.SessionOptions), entranceURL); err != nil {
+		return nil, fmt.Errorf("role %s: %w", profile.Name, err)
+	}
+	conf.HTTPClient = newSessionClient(conf.Session)
+	conf.LoginScript = nil
+	if profile.LoginScript != "" {
+		if conf.LoginScript, err = loadLoginScript(profile.LoginScript, profile.TOTPSecret); err != nil {
+			return nil, fmt.Errorf("role %s: %w", profile.Name, err)
+		}
+	}
+	// 会话监控记录重新登录状态，每个角色独立
+	if conf.SessionMonitor, err = NewSessionMonitor(monitorConf); err != nil {
+		return nil, err
+	}
+	return &conf, nil
+}
+
+// roleCrawl 单个角色的爬取配置和结果
+type roleCrawl struct {
+	Name    string
+	Conf    *TabConfig
+	Store   *RequestStore
+	Skipped bool // 爬取时间用尽或浏览器启动失败，未以该角色爬取
+}
+
+// crawlRoles 依次以每个角色爬取，每个角色使用独立的浏览器上下文和请求存储，新请求汇总到 store
+func crawlRoles(store *RequestStore, allocCtx context.Context, roles []*roleCrawl, profiles []*EmulationProfile, progressStats *ProgressStats) {
+	// 先启动浏览器，角色的浏览器上下文在其中创建
+	browserCtx, cancel := chromedp.NewContext(allocCtx)
+	defer cancel()
+	if err := chromedp.Run(browserCtx); err != nil {
+		GetGlobalLogger().Error("Failed to start browser", err)
+		for _, role := range roles {
+			role.Skipped = true
+		}
+		return
+	}
+
+	seeds := store.GetRequests()
+	for _, role := range roles {
+		if browserCtx.Err() != nil {
+			// 所有角色共享 -crawl_total_time，时间用尽后剩余的角色不再爬取，也不参与回放
+			role.Skipped = true
+			GetGlobalLogger().Warn(fmt.Sprintf("Crawl time exhausted, skipping role %s", role.Name))
+			continue
+		}
+		progressStats.UpdateField("phase", "Crawling as "+role.Name)
+		GetGlobalLogger().Info(fmt.Sprintf("Crawling as role %s", role.Name))
+
+		// 入口、种子和导入的请求作为每个角色的起点
+		for _, req := range seeds {
+			role.Store.SaveRequest(req)
+		}
+		crawlProfiles(role.Store, browserCtx, role.Conf, profiles, progressStats)
+	}
+}
+
+// AccessResult 某个角色回放请求的结果
+type AccessResult struct {
+	Status     int     `json:"status"`
+	Length     int     `json:"length"`
+	Similarity float64 `json:"similarity"` // 与发现该请求的角色响应的相似度
+	Error      string  `json:"error,omitempty"`
+}
+
+// AccessEntry 访问控制矩阵的一行
+type AccessEntry struct {
+	Method       string                  `json:"method"`
+	URL          string                  `json:"url"`
+	Pattern      string                  `json:"pattern"`
+	DiscoveredBy []string                `json:"discovered_by"`
+	Results      map[string]AccessResult `json:"results"`
+}
+
+// AccessMatrix 多角色访问控制对比报告
+type AccessMatrix struct {
+	Roles   []string      `json:"roles"`
+	Skipped []string      `json:"skipped_roles,omitempty"` // 未爬取的角色，不参与回放
+	Entries []AccessEntry `json:"entries"`
+}
+
+// isSafeMethod 是否为默认回放的安全方法
+func isSafeMethod(method string) bool {
+	return method == http.MethodGet || method == http.MethodHead || method == http.MethodOptions
+}
+
+// collectReplayTargets 汇总各角色发现的请求，记录发现该请求的角色
+func collectReplayTargets(roles []*roleCrawl, includeUnsafe bool) ([]request, map[string][]string) {
+	targets := make([]request, 0)
+	discoveredBy := make(map[string][]string)
+	for _, role := range roles {
+		for _, req := range role.Store.GetRequests() {
+			if !includeUnsafe && !isSafeMethod(req.Method) {
+				continue
+			}
+			if strings.Contains(strings.ToLower(req.URL), "logout") || req.WebSocket != nil {
+				// WebSocket 端点无法以 HTTP 请求回放
+				continue
+			}
+			key := req.Method + " " + req.URL
+			if _, ok := discoveredBy[key]; !ok {
+				targets = append(targets, req)
+			}
+			discoveredBy[key] = append(discoveredBy[key], role.Name)
+		}
+	}
+	return targets, discoveredBy
+}
+
+// newReplayRequest 创建回放请求，去掉原角色的会话相关请求头，由回放角色的客户端重新添加
+func newReplayRequest(req request, roles []*roleCrawl) (*http.Request, error) {
+	var body io.Reader
+	if req.Data != "" {
+		data, err := b64.StdEncoding.DecodeString(req.Data)
+		if err != nil {
+			return nil, err
+		}
+		body = bytes.NewReader(data)
+	}
+	httpReq, err := http.NewRequest(req.Method, req.URL, body)
+	if err != nil {
+		return nil, err
+	}
+	for name, value := range req.Headers {
+		strValue, ok := value.(string)
+		if !ok || strValue == "" || isImportDropHeader(name) {
+			continue
+		}
+		scoped := false
+		for _, role := range roles {
+			if role.Conf.Session.isScopedHeader(name) {
+				scoped = true
+				break
+			}
+		}
+		if !scoped {
+			httpReq.Header.Set(name, strValue)
+		}
+	}
+	return httpReq, nil
+}
+
+// replayAs 以指定客户端回放请求，返回状态码和响应体
+func replayAs(client *http.Client, req request, roles []*roleCrawl) (int, []byte, error) {
+	httpReq, err := newReplayRequest(req, roles)
+	if err != nil {
+		return 0, nil, err
+	}
+	resp, err := client.Do(httpReq)
+	if err != nil {
+		return 0, nil, err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxReplayBodySize))
+	return resp.StatusCode, body, err
+}
+
+// replayAcrossRoles 以每个角色的会话回放所有角色发现的请求，生成访问控制矩阵
+// 未爬取的角色没有登录，回放结果与其它角色不可比，只记录在 skipped_roles 中
+func replayAcrossRoles(ctx context.Context, roles []*roleCrawl, includeUnsafe bool) *AccessMatrix {
+	matrix := &AccessMatrix{}
+	crawled := make([]*roleCrawl, 0, len(roles))
+	for _, role := range roles {
+		if role.Skipped {
+			matrix.Skipped = append(matrix.Skipped, role.Name)
+			continue
+		}
+		crawled = append(crawled, role)
+	}
+	roles = crawled
+
+	targets, discoveredBy := collectReplayTargets(roles, includeUnsafe)
+	clients := make(map[string]*http.Client, len(roles))
+	matrix.Entries = make([]AccessEntry, len(targets))
+	for _, role := range roles {
+		clients[role.Name] = role.Conf.HTTPClient
+		matrix.Roles = append(matrix.Roles, role.Name)
+	}
+	GetGlobalLogger().Info(fmt.Sprintf("Replaying %d requests as %d roles", len(targets), len(roles)))
+
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, replayConcurrency)
+	for i, req := range targets {
+		if ctx.Err() != nil {
+			break
+		}
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int, req request) {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			key := req.Method + " " + req.URL
+			entry := AccessEntry{
+				Method:       req.Method,
+				URL:          req.URL,
+				Pattern:      urlPattern(req.URL),
+				DiscoveredBy: discoveredBy[key],
+				Results:      make(map[string]AccessResult, len(roles)),
+			}
+			bodies := make(map[string][]byte, len(roles))
+			for _, role := range roles {
+				status, body, err := replayAs(clients[role.Name], req, roles)
+				result := AccessResult{Status: status, Length: len(body)}
+				if err != nil {
+					result.Error = err.Error()
+				}
+				entry.Results[role.Name] = result
+				bodies[role.Name] = body
+			}
+
+			// 以首个发现该请求的角色的响应为基准
+			owner := entry.DiscoveredBy[0]
+			for name, result := range entry.Results {
+				result.Similarity = responseSimilarity(bodies[owner], bodies[name])
+				entry.Results[name] = result
+			}
+			matrix.Entries[i] = entry
+		}(i, req)
+	}
+	wg.Wait()
+
+	// 去掉因取消而未回放的行
+	entries := matrix.Entries[:0]
+	for _, entry := range matrix.Entries {
+		if entry.Results != nil {
+			entries = append(entries, entry)
+		}
+	}
+	matrix.Entries = entries
+	return matrix
+}
+
+// responseTokens 将响应体切分为小写词元
+func responseTokens(body []byte) []string {
+	return strings.FieldsFunc(strings.ToLower(string(body)), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// responseSimilarity 计算两个响应体词元二元组集合的 Jaccard 相似度，取值 0 到 1
+func responseSimilarity(a, b []byte) float64 {
+	if bytes.Equal(a, b) {
+		return 1
+	}
+	shingles := func(body []byte) map[string]bool {
+		tokens := responseTokens(body)
+		set := make(map[string]bool, len(tokens))
+		if len(tokens) == 1 {
+			set[tokens[0]] = true
+		}
+		for i := 0; i+1 < len(tokens); i++ {
+			set[tokens[i]+" "+tokens[i+1]] = true
+		}
+		return set
+	}
+	setA, setB := shingles(a), shingles(b)
+	if len(setA) == 0 && len(setB) == 0 {
+		return 1
+	}
+	intersection := 0
+	for shingle := range setA {
+		if setB[shingle] {
+			intersection++
+		}
+	}
+	union := len(setA) + len(setB) - intersection
+	return float64(int(float64(intersection)/float64(union)*1000)) / 1000
+}
+
+// accessible 判断角色是否可以访问端点：2xx 且响应与发现者相似
+func (r AccessResult) accessible() bool {
+	return r.Error == "" && r.Status >= 200 && r.Status < 300 && r.Similarity >= accessSimilarityThreshold
+}
+
+// outputAccessMatrix 输出访问控制矩阵，路径以 .csv 结尾时输出 CSV，否则输出 JSON
+func outputAccessMatrix(matrix *AccessMatrix, path string) error {
+	sort.Slice(matrix.Entries, func(i, j int) bool {
+		if matrix.Entries[i].URL != matrix.Entries[j].URL {
+			return matrix.Entries[i].URL < matrix.Entries[j].URL
+		}
+		return matrix.Entries[i].Method < matrix.Entries[j].Method
+	})
+
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	if !strings.HasSuffix(strings.ToLower(path), ".csv") {
+		encoder := json.NewEncoder(file)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(matrix)
+	}
+
+	writer := csv.NewWriter(file)
+	header := []string{"method", "url", "pattern", "discovered_by"}
+	for _, role := range matrix.Roles {
+		header = append(header, role+"_status", role+"_similarity", role+"_accessible")
+	}
+	if err := writer.Write(header); err != nil {
+		return err
+	}
+	for _, entry := range matrix.Entries {
+		row := []string{entry.Method, entry.URL, entry.Pattern, strings.Join(entry.DiscoveredBy, ";")}
+		for _, role := range matrix.Roles {
+			result := entry.Results[role]
+			row = append(row,
+				strconv.Itoa(result.Status),
+				strconv.FormatFloat(result.Similarity, 'f', 3, 64),
+				strconv.FormatBool(result.accessible()),
+			)
+		}
+		if err := writer.Write(row); err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+// summarizeAccessMatrix 统计每个角色可访问的、由其它角色发现的端点数
+func summarizeAccessMatrix(matrix *AccessMatrix) map[string]int {
+	summary := make(map[string]int, len(matrix.Roles))
+	for _, entry := range matrix.Entries {
+		for _, role := range matrix.Roles {
+			discovered := false
+			for _, name := range entry.DiscoveredBy {
+				if name == role {
+					discovered = true
+					break
+				}
+			}
+			if !discovered && entry.Results[role].accessible() {
+				summary[role]++
+			}
+		}
+	}
+	return summary
+}
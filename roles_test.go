@@ -0,0 +1,92 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestLoadRoleProfiles(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+
+	path := write("roles.json", `{"roles": [
+		{"name": "admin", "cookie_file": "admin.txt", "login_script": "/abs/login.json", "totp_secret": "JBSWY3DPEHPK3PXP"},
+		{"name": "anonymous"}
+	]}`)
+	roles, err := loadRoleProfiles(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(roles) != 2 || roles[0].Name != "admin" || roles[1].Name != "anonymous" {
+		t.Fatalf("roles = %+v", roles)
+	}
+	if want := filepath.Join(dir, "admin.txt"); roles[0].CookieFile != want {
+		t.Errorf("CookieFile = %q, want %q", roles[0].CookieFile, want)
+	}
+	if roles[0].LoginScript != "/abs/login.json" || roles[0].TOTPSecret != "JBSWY3DPEHPK3PXP" {
+		t.Errorf("LoginScript = %q, TOTPSecret = %q", roles[0].LoginScript, roles[0].TOTPSecret)
+	}
+	if roles[1].CookieFile != "" || roles[1].LoginScript != "" {
+		t.Errorf("empty paths resolved: %+v", roles[1])
+	}
+
+	for name, content := range map[string]string{
+		"single.json":    `{"roles": [{"name": "admin"}]}`,
+		"duplicate.json": `{"roles": [{"name": "admin"}, {"name": "admin"}]}`,
+		"unnamed.json":   `{"roles": [{"name": "admin"}, {}]}`,
+		"invalid.json":   `{"roles": [`,
+	} {
+		if _, err := loadRoleProfiles(write(name, content)); err == nil {
+			t.Errorf("%s: expected error", name)
+		}
+	}
+}
+
+func TestMergeSessionOptions(t *testing.T) {
+	base := SessionOptions{
+		Cookie:      "theme=dark",
+		CookieFile:  "base.txt",
+		Headers:     stringList{"X-Base: 1"},
+		HTTPAuth:    stringList{"app.example.com=user:pass"},
+		BearerToken: "base-token",
+	}
+	role := SessionOptions{
+		Cookie:      "sid=1; bad; lang=en",
+		Headers:     stringList{"X-Role: 2"},
+		BearerToken: "role-token",
+	}
+	merged := mergeSessionOptions(base, role)
+	want := SessionOptions{
+		Cookie:      "sid=1; lang=en",
+		CookieFile:  "base.txt",
+		Headers:     stringList{"X-Base: 1", "X-Role: 2"},
+		HTTPAuth:    stringList{"app.example.com=user:pass"},
+		BearerToken: "role-token",
+	}
+	if !reflect.DeepEqual(merged, want) {
+		t.Errorf("merged = %+v, want %+v", merged, want)
+	}
+	// 合并不修改全局配置的列表
+	if len(base.Headers) != 1 || base.Headers[0] != "X-Base: 1" {
+		t.Errorf("base modified: %+v", base.Headers)
+	}
+}
+
+func TestReplayAcrossRolesSkipsUncrawledRoles(t *testing.T) {
+	roles := []*roleCrawl{
+		{Name: "admin", Conf: &TabConfig{}, Store: NewRequestStore()},
+		{Name: "user", Conf: &TabConfig{}, Store: NewRequestStore(), Skipped: true},
+	}
+	matrix := replayAcrossRoles(t.Context(), roles, false)
+	if !reflect.DeepEqual(matrix.Roles, []string{"admin"}) || !reflect.DeepEqual(matrix.Skipped, []string{"user"}) {
+		t.Errorf("Roles = %v, Skipped = %v", matrix.Roles, matrix.Skipped)
+	}
+}
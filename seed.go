@@ -8,7 +8,6 @@ import (
 	"net/http"
 	"net/url"
 	"strings"
-	"time"
 )
 
 // SitemapURL sitemap.xml 中的 URL 结构
@@ -22,22 +21,22 @@ type Sitemap struct {
 }
 
 // fetchSeedUrls 从 robots.txt 和 sitemap.xml 获取种子 URL
-func fetchSeedUrls(baseURL string, headers map[string]interface{}) []string {
+func fetchSeedUrls(client *http.Client, baseURL string, headers map[string]interface{}) []string {
 	var seedUrls []string
 	
 	// 解析 robots.txt
-	robotsUrls := parseRobotsTxt(baseURL, headers)
+	robotsUrls := parseRobotsTxt(client, baseURL, headers)
 	seedUrls = append(seedUrls, robotsUrls...)
 	
 	// 解析 sitemap.xml
-	sitemapUrls := parseSitemapXml(baseURL, headers)
+	sitemapUrls := parseSitemapXml(client, baseURL, headers)
 	seedUrls = append(seedUrls, sitemapUrls...)
 	
 	return seedUrls
 }
 
 // parseRobotsTxt 解析 robots.txt 获取路径
-func parseRobotsTxt(baseURL string, headers map[string]interface{}) []string {
+func parseRobotsTxt(client *http.Client, baseURL string, headers map[string]interface{}) []string {
 	var urls []string
 	
 	u, err := url.Parse(baseURL)
@@ -47,25 +46,12 @@ func parseRobotsTxt(baseURL string, headers map[string]interface{}) []string {
 	
 	robotsURL := u.Scheme + "://" + u.Host + "/robots.txt"
 	
-	req, err := http.NewRequest("GET", robotsURL, nil)
+	// 设置请求头，cookie 由共享的会话 cookie jar 携带
+	req, err := newGoRequest(client, http.MethodGet, robotsURL, headers)
 	if err != nil {
 		return urls
 	}
 	
-	// 设置请求头
-	for key, value := range headers {
-		if strValue, ok := value.(string); ok {
-			req.Header.Set(key, strValue)
-		}
-	}
-	
-	client := &http.Client{
-		Timeout: 10 * time.Second,
-		CheckRedirect: func(req *http.Request, via []*http.Request) error {
-			return http.ErrUseLastResponse
-		},
-	}
-	
 	resp, err := client.Do(req)
 	if err != nil || resp.StatusCode != 200 {
 		return urls
@@ -96,7 +82,7 @@ func parseRobotsTxt(baseURL string, headers map[string]interface{}) []string {
 				sitemapURL := strings.TrimSpace(parts[1])
 				if strings.HasPrefix(sitemapURL, "http") {
 					// 解析这个 sitemap
-					sitemapUrls := parseSitemapFromURL(sitemapURL, headers)
+					sitemapUrls := parseSitemapFromURL(client, sitemapURL, headers)
 					urls = append(urls, sitemapUrls...)
 				}
 			}
@@ -111,7 +97,7 @@ func parseRobotsTxt(baseURL string, headers map[string]interface{}) []string {
 }
 
 // parseSitemapXml 解析 sitemap.xml 获取 URL
-func parseSitemapXml(baseURL string, headers map[string]interface{}) []string {
+func parseSitemapXml(client *http.Client, baseURL string, headers map[string]interface{}) []string {
 	var urls []string
 	
 	u, err := url.Parse(baseURL)
@@ -120,32 +106,19 @@ func parseSitemapXml(baseURL string, headers map[string]interface{}) []string {
 	}
 	
 	sitemapURL := u.Scheme + "://" + u.Host + "/sitemap.xml"
-	return parseSitemapFromURL(sitemapURL, headers)
+	return parseSitemapFromURL(client, sitemapURL, headers)
 }
 
 // parseSitemapFromURL 从指定 URL 解析 sitemap
-func parseSitemapFromURL(sitemapURL string, headers map[string]interface{}) []string {
+func parseSitemapFromURL(client *http.Client, sitemapURL string, headers map[string]interface{}) []string {
 	var urls []string
 	
-	req, err := http.NewRequest("GET", sitemapURL, nil)
+	// 设置请求头，cookie 由共享的会话 cookie jar 携带
+	req, err := newGoRequest(client, http.MethodGet, sitemapURL, headers)
 	if err != nil {
 		return urls
 	}
 	
-	// 设置请求头
-	for key, value := range headers {
-		if strValue, ok := value.(string); ok {
-			req.Header.Set(key, strValue)
-		}
-	}
-	
-	client := &http.Client{
-		Timeout: 10 * time.Second,
-		CheckRedirect: func(req *http.Request, via []*http.Request) error {
-			return http.ErrUseLastResponse
-		},
-	}
-	
 	resp, err := client.Do(req)
 	if err != nil || resp.StatusCode != 200 {
 		return urls
@@ -0,0 +1,385 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/cookiejar"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/chromedp/cdproto/cdp"
+	"github.com/chromedp/cdproto/network"
+)
+
+// Session 会话状态：登录产生的 cookie 和 Web Storage，由所有标签页共享
+type Session struct {
+	mu             sync.RWMutex
+	jar            *cookiejar.Jar
+	cookies        map[string]*network.Cookie   // key: domain + path + name
+	localStorage   map[string]map[string]string // origin -> key/value
+	sessionStorage map[string]map[string]string // origin -> key/value
+	headerRules    []HeaderRule                 // 按主机作用域注入的请求头
+	headerNames    map[string]bool              // 作用域请求头名（小写）
+	credentials    []HTTPCredential             // HTTP 认证凭据
+	tokens         *tokenTracker                // 动态令牌来源
+}
+
+// NewSession 创建空会话
+func NewSession() *Session {
+	jar, _ := cookiejar.New(nil)
+	return &Session{
+		jar:            jar,
+		cookies:        make(map[string]*network.Cookie),
+		localStorage:   make(map[string]map[string]string),
+		sessionStorage: make(map[string]map[string]string),
+		tokens:         newTokenTracker(),
+	}
+}
+
+// cookieKey cookie 唯一键
+func cookieKey(c *network.Cookie) string {
+	return c.Domain + c.Path + "\x00" + c.Name
+}
+
+// cookieURL 根据 cookie 的作用域构造 URL，用于写入 cookiejar
+func cookieURL(c *network.Cookie) *url.URL {
+	scheme := "http"
+	if c.Secure {
+		scheme = "https"
+	}
+	path := c.Path
+	if path == "" {
+		path = "/"
+	}
+	return &url.URL{Scheme: scheme, Host: strings.TrimPrefix(c.Domain, "."), Path: path}
+}
+
+// toHTTPCookie 转换为 net/http cookie
+func toHTTPCookie(c *network.Cookie) *http.Cookie {
+	hc := &http.Cookie{
+		Name:     c.Name,
+		Value:    c.Value,
+		Path:     c.Path,
+		Secure:   c.Secure,
+		HttpOnly: c.HTTPOnly,
+	}
+	// 以点开头的 domain 为域 cookie，否则为主机 cookie
+	if strings.HasPrefix(c.Domain, ".") {
+		hc.Domain = c.Domain
+	}
+	if !c.Session && c.Expires > 0 {
+		hc.Expires = time.Unix(int64(c.Expires), 0)
+	}
+	return hc
+}
+
+// fromHTTPCookie 将 Go 端响应中的 Set-Cookie 转换为浏览器 cookie
+func fromHTTPCookie(u *url.URL, hc *http.Cookie) *network.Cookie {
+	c := &network.Cookie{
+		Name:     hc.Name,
+		Value:    hc.Value,
+		Domain:   u.Hostname(),
+		Path:     hc.Path,
+		Secure:   hc.Secure,
+		HTTPOnly: hc.HttpOnly,
+		Session:  true,
+	}
+	if hc.Domain != "" {
+		c.Domain = "." + strings.TrimPrefix(hc.Domain, ".")
+	}
+	if c.Path == "" {
+		c.Path = "/"
+	}
+	if !hc.Expires.IsZero() {
+		c.Expires = float64(hc.Expires.Unix())
+		c.Session = false
+	}
+	return c
+}
+
+// sessionJar 会话 cookie 的 http.CookieJar 视图，Go 端请求与浏览器共享同一份 cookie
+type sessionJar struct {
+	s *Session
+}
+
+// SetCookies 记录 Go 端响应中的 Set-Cookie
+func (j sessionJar) SetCookies(u *url.URL, cookies []*http.Cookie) {
+	j.s.mu.Lock()
+	defer j.s.mu.Unlock()
+	j.s.jar.SetCookies(u, cookies)
+	for _, hc := range cookies {
+		c := fromHTTPCookie(u, hc)
+		if hc.MaxAge < 0 || (!c.Session && hc.Expires.Before(time.Now())) {
+			delete(j.s.cookies, cookieKey(c))
+			continue
+		}
+		j.s.cookies[cookieKey(c)] = c
+	}
+}
+
+// Cookies 获取指定 URL 可用的 cookie
+func (j sessionJar) Cookies(u *url.URL) []*http.Cookie {
+	j.s.mu.RLock()
+	defer j.s.mu.RUnlock()
+	return j.s.jar.Cookies(u)
+}
+
+// Jar 获取供 Go 端 HTTP 客户端使用的 cookie jar
+func (s *Session) Jar() http.CookieJar {
+	if s == nil {
+		return nil
+	}
+	return sessionJar{s: s}
+}
+
+// SetCookies 合并浏览器 cookie 到会话
+func (s *Session) SetCookies(cookies []*network.Cookie) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, c := range cookies {
+		s.cookies[cookieKey(c)] = c
+		s.jar.SetCookies(cookieURL(c), []*http.Cookie{toHTTPCookie(c)})
+	}
+}
+
+// SetStorage 保存指定源的 localStorage 和 sessionStorage
+func (s *Session) SetStorage(origin string, local, session map[string]string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(local) > 0 {
+		s.localStorage[origin] = local
+	}
+	if len(session) > 0 {
+		s.sessionStorage[origin] = session
+	}
+}
+
+// HasCookies 会话中是否有 cookie
+func (s *Session) HasCookies() bool {
+	if s == nil {
+		return false
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return len(s.cookies) > 0
+}
+
+// CookieHeader 获取指定 URL 可用的 cookie 请求头
+func (s *Session) CookieHeader(rawURL string) string {
+	if s == nil {
+		return ""
+	}
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return ""
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	pairs := make([]string, 0)
+	for _, c := range s.jar.Cookies(u) {
+		pairs = append(pairs, c.Name+"="+c.Value)
+	}
+	return strings.Join(pairs, "; ")
+}
+
+// ApplyHeaders 返回请求头副本，会话 cookie 覆盖原 Cookie 头中的同名项，并加入该 URL 作用域内的请求头
+func (s *Session) ApplyHeaders(rawURL string, headers map[string]interface{}) map[string]interface{} {
+	sessionCookie := s.CookieHeader(rawURL)
+	scoped := s.HeadersFor(rawURL)
+	if sessionCookie == "" && len(scoped) == 0 {
+		return headers
+	}
+
+	result := make(map[string]interface{}, len(headers)+len(scoped)+1)
+	var original string
+	for name, value := range headers {
+		if strings.EqualFold(name, "Cookie") {
+			original, _ = value.(string)
+			continue
+		}
+		if s.isScopedHeader(name) {
+			continue
+		}
+		result[name] = value
+	}
+	if merged := mergeCookieHeader(original, sessionCookie); merged != "" {
+		result["Cookie"] = merged
+	}
+	for name, value := range scoped {
+		result[name] = value
+	}
+	return result
+}
+
+// BrowserHeaders 返回通过 SetExtraHTTPHeaders 发送给浏览器的请求头副本
+// 会话中有 cookie 时去掉 Cookie 头，由浏览器按域名、路径作用域自行携带，并接收应用的 Set-Cookie 更新；
+// 作用域请求头在请求拦截时按主机注入，这里一并去掉，避免泄露给第三方源
+func (s *Session) BrowserHeaders(headers map[string]interface{}) map[string]interface{} {
+	hasCookies := s.HasCookies()
+	if !hasCookies && !s.HasHeaderRules() {
+		return headers
+	}
+	result := make(map[string]interface{}, len(headers))
+	for name, value := range headers {
+		if (hasCookies && strings.EqualFold(name, "Cookie")) || s.isScopedHeader(name) {
+			continue
+		}
+		result[name] = value
+	}
+	return result
+}
+
+// mergeCookieHeader 合并两个 Cookie 头，后者优先
+func mergeCookieHeader(base, override string) string {
+	names := make([]string, 0)
+	values := make(map[string]string)
+	for _, header := range []string{base, override} {
+		for _, pair := range strings.Split(header, ";") {
+			name, value, found := strings.Cut(strings.TrimSpace(pair), "=")
+			if !found || name == "" {
+				continue
+			}
+			if _, ok := values[name]; !ok {
+				names = append(names, name)
+			}
+			values[name] = value
+		}
+	}
+	pairs := make([]string, 0, len(names))
+	for _, name := range names {
+		pairs = append(pairs, name+"="+values[name])
+	}
+	return strings.Join(pairs, "; ")
+}
+
+// CookieParams 获取可通过 network.SetCookies 写入浏览器的 cookie
+func (s *Session) CookieParams() []*network.CookieParam {
+	if s == nil {
+		return nil
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	params := make([]*network.CookieParam, 0, len(s.cookies))
+	for _, c := range s.cookies {
+		param := &network.CookieParam{
+			Name:     c.Name,
+			Value:    c.Value,
+			Domain:   c.Domain,
+			Path:     c.Path,
+			Secure:   c.Secure,
+			HTTPOnly: c.HTTPOnly,
+			SameSite: c.SameSite,
+		}
+		// 主机 cookie 通过 URL 指定，避免被扩展为域 cookie
+		if !strings.HasPrefix(c.Domain, ".") {
+			param.Domain = ""
+			param.URL = cookieURL(c).String()
+		}
+		if !c.Session && c.Expires > 0 {
+			expires := cdp.TimeSinceEpoch(time.Unix(int64(c.Expires), 0))
+			param.Expires = &expires
+		}
+		params = append(params, param)
+	}
+	return params
+}
+
+// StorageScript 生成在新文档中写入 Web Storage 的脚本，无数据时返回空字符串
+func (s *Session) StorageScript() string {
+	if s == nil {
+		return ""
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if len(s.localStorage) == 0 && len(s.sessionStorage) == 0 {
+		return ""
+	}
+	local, _ := json.Marshal(s.localStorage)
+	session, _ := json.Marshal(s.sessionStorage)
+	return fmt.Sprintf(`(function(local, session) {
+		// 仅写入不存在的项，避免覆盖应用自行刷新的值
+		function seed(storage, items) {
+			if (!items) return;
+			try {
+				Object.keys(items).forEach(k => {
+					if (storage.getItem(k) === null) storage.setItem(k, items[k]);
+				});
+			} catch (e) {}
+		}
+		seed(window.localStorage, local[location.origin]);
+		seed(window.sessionStorage, session[location.origin]);
+	})(%s, %s);`, local, session)
+}
+
+// SessionOptions 会话的认证来源，对应命令行参数和角色配置中的同名字段
+type SessionOptions struct {
+	Cookie      string     `json:"cookie,omitempty"`
+	CookieFile  string     `json:"cookie_file,omitempty"`
+	StorageFile string     `json:"storage_file,omitempty"`
+	Headers     stringList `json:"headers,omitempty"`
+	HeaderFile  string     `json:"header_file,omitempty"`
+	BearerToken string     `json:"bearer_token,omitempty"`
+	HTTPAuth    stringList `json:"http_auth,omitempty"`
+}
+
+// buildSession 按认证来源创建会话，未指定主机的请求头和凭据作用于入口 URL 主机
+func buildSession(opts SessionOptions, entranceURL string) (*Session, error) {
+	session := NewSession()
+	entranceHost := hostOf(entranceURL)
+
+	// cookie 和 Web Storage
+	session.SetCookies(parseCookieHeader(opts.Cookie, entranceURL))
+	if opts.CookieFile != "" {
+		cookies, err := loadCookieFile(opts.CookieFile)
+		if err != nil {
+			return nil, err
+		}
+		session.SetCookies(cookies)
+		GetGlobalLogger().Info(fmt.Sprintf("Imported %d cookies from %s", len(cookies), opts.CookieFile))
+	}
+	if opts.StorageFile != "" {
+		entries, err := loadStorageFile(opts.StorageFile)
+		if err != nil {
+			return nil, err
+		}
+		for origin, entry := range entries {
+			session.SetStorage(origin, entry.LocalStorage, entry.SessionStorage)
+		}
+	}
+
+	// 按主机作用域注入的自定义请求头
+	var headerRules []HeaderRule
+	if opts.HeaderFile != "" {
+		rules, err := loadHeaderFile(opts.HeaderFile, entranceHost)
+		if err != nil {
+			return nil, err
+		}
+		headerRules = append(headerRules, rules...)
+	}
+	for _, line := range opts.Headers {
+		rule, err := parseHeaderRule(line, entranceHost)
+		if err != nil {
+			return nil, err
+		}
+		headerRules = append(headerRules, rule)
+	}
+	if opts.BearerToken != "" {
+		headerRules = append(headerRules, HeaderRule{Host: entranceHost, Name: "Authorization", Value: "Bearer " + opts.BearerToken})
+	}
+	session.SetHeaderRules(headerRules)
+
+	// HTTP 认证凭据
+	var credentials []HTTPCredential
+	for _, line := range opts.HTTPAuth {
+		cred, err := parseHTTPCredential(line, entranceHost)
+		if err != nil {
+			return nil, err
+		}
+		credentials = append(credentials, cred)
+	}
+	session.SetCredentials(credentials)
+	return session, nil
+}
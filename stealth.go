@@ -0,0 +1,227 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/chromedp/cdproto/browser"
+	"github.com/chromedp/cdproto/cdp"
+	"github.com/chromedp/cdproto/emulation"
+	"github.com/chromedp/cdproto/page"
+	"github.com/chromedp/chromedp"
+)
+
+// 隐身配置
+const (
+	StealthOff     = "off"     // 不注入隐身脚本
+	StealthMinimal = "minimal" // 仅隐藏 navigator.webdriver
+	StealthChrome  = "chrome"  // 与真实 Chrome 一致的完整指纹
+)
+
+// StealthRule 按主机作用域选择的隐身配置，只决定页面中注入的隐身脚本的行为
+type StealthRule struct {
+	Host    string `json:"host"` // 主机，格式同 HeaderRule.Host
+	Profile string `json:"profile"`
+}
+
+// StealthConfig 隐身层配置
+type StealthConfig struct {
+	Rules     []StealthRule // 后定义的规则优先，第一条为默认规则 "*"
+	UserAgent string        // 显式指定的 -ua，为空则使用真实浏览器版本的 User-Agent
+}
+
+// parseStealthRules 解析 -stealth 参数："profile" 或 "[host] profile"，未指定主机时作用于所有主机
+func parseStealthRules(values []string) ([]StealthRule, error) {
+	rules := []StealthRule{{Host: "*", Profile: StealthChrome}}
+	for _, value := range values {
+		host, profile, err := splitHostScope(value, "*")
+		if err != nil {
+			return nil, err
+		}
+		profile = strings.ToLower(profile)
+		switch profile {
+		case StealthOff, StealthMinimal, StealthChrome:
+		default:
+			return nil, fmt.Errorf("invalid stealth %q, expected \"[host] chrome|minimal|off\"", value)
+		}
+		// 作用于所有主机的规则覆盖之前的全部规则，Enabled 不再受被覆盖的规则影响
+		if host == "*" {
+			rules = rules[:0]
+		}
+		rules = append(rules, StealthRule{Host: host, Profile: profile})
+	}
+	return rules, nil
+}
+
+// Enabled 是否有主机启用了隐身层
+func (c *StealthConfig) Enabled() bool {
+	if c == nil {
+		return false
+	}
+	for _, rule := range c.Rules {
+		if rule.Profile != StealthOff {
+			return true
+		}
+	}
+	return false
+}
+
+// stealthFingerprint 注入页面的指纹，与 User-Agent 保持一致
+type stealthFingerprint struct {
+	Languages     []string `json:"languages,omitempty"`
+	Platform      string   `json:"platform,omitempty"`
+	DeviceMemory  int      `json:"device_memory,omitempty"`
+	Mobile        bool     `json:"mobile"`
+	WebGLVendor   string   `json:"webgl_vendor,omitempty"`
+	WebGLRenderer string   `json:"webgl_renderer,omitempty"`
+}
+
+// browserIdentity 真实浏览器版本对应的 User-Agent 和 Client Hints（去掉无头标识）
+type browserIdentity struct {
+	UserAgent string
+	Major     string // Chrome 主版本号
+	Platform  string // navigator.platform
+	Metadata  *emulation.UserAgentMetadata
+}
+
+var (
+	identityOnce   sync.Once
+	cachedIdentity *browserIdentity
+	identityErr    error
+)
+
+// browserUserAgent 在临时标签页中获取真实浏览器的 User-Agent（去掉 HeadlessChrome 标识），与隐身层覆盖的 User-Agent 相同
+func browserUserAgent(allocCtx context.Context) (string, error) {
+	ctx, cancel := chromedp.NewContext(allocCtx)
+	defer cancel()
+	var id *browserIdentity
+	err := chromedp.Run(ctx, chromedp.ActionFunc(func(ctx context.Context) error {
+		var err error
+		id, err = resolveBrowserIdentity(ctx)
+		return err
+	}))
+	if err != nil {
+		return "", err
+	}
+	return id.UserAgent, nil
+}
+
+// resolveBrowserIdentity 通过 Browser.getVersion 获取浏览器版本，生成去掉 HeadlessChrome 标识的 User-Agent 和 Client Hints
+func resolveBrowserIdentity(ctx context.Context) (*browserIdentity, error) {
+	identityOnce.Do(func() {
+		c := chromedp.FromContext(ctx)
+		_, product, _, userAgent, _, err := browser.GetVersion().Do(cdp.WithExecutor(ctx, c.Browser))
+		if err != nil {
+			identityErr = err
+			return
+		}
+		_, version, _ := strings.Cut(product, "/")
+		major, _, _ := strings.Cut(version, ".")
+		id := &browserIdentity{UserAgent: strings.Replace(userAgent, "HeadlessChrome/", "Chrome/", 1), Major: major}
+		metadata := &emulation.UserAgentMetadata{Architecture: "x86", Bitness: "64"}
+		switch {
+		case strings.Contains(userAgent, "Windows"):
+			id.Platform, metadata.Platform, metadata.PlatformVersion = "Win32", "Windows", "10.0.0"
+		case strings.Contains(userAgent, "Macintosh"):
+			id.Platform, metadata.Platform, metadata.PlatformVersion = "MacIntel", "macOS", "14.0.0"
+		case strings.Contains(userAgent, "Android"):
+			id.Platform, metadata.Platform, metadata.Architecture, metadata.Bitness = "Linux armv81", "Android", "", ""
+			metadata.Mobile = strings.Contains(userAgent, "Mobile")
+		default:
+			id.Platform, metadata.Platform = "Linux x86_64", "Linux"
+		}
+		for _, brand := range []struct{ name, version string }{
+			{"Google Chrome", major}, {"Chromium", major}, {"Not)A;Brand", "8"},
+		} {
+			full := version
+			if brand.version != major {
+				full = brand.version + ".0.0.0"
+			}
+			metadata.Brands = append(metadata.Brands, &emulation.UserAgentBrandVersion{Brand: brand.name, Version: brand.version})
+			metadata.FullVersionList = append(metadata.FullVersionList, &emulation.UserAgentBrandVersion{Brand: brand.name, Version: full})
+		}
+		id.Metadata = metadata
+		cachedIdentity = id
+	})
+	return cachedIdentity, identityErr
+}
+
+// webGLIdentity 与 navigator.platform 匹配的 WebGL 厂商和渲染器
+func webGLIdentity(platform string) (string, string) {
+	switch {
+	case strings.HasPrefix(platform, "Win"):
+		return "Google Inc. (Intel)", "ANGLE (Intel, Intel(R) UHD Graphics 620 Direct3D11 vs_5_0 ps_5_0, D3D11)"
+	case strings.HasPrefix(platform, "Mac"):
+		return "Google Inc. (Apple)", "ANGLE (Apple, ANGLE Metal Renderer: Apple M1, Unspecified Version)"
+	case platform == "iPhone" || platform == "iPad":
+		return "Apple Inc.", "Apple GPU"
+	case strings.Contains(platform, "arm"):
+		return "Qualcomm", "Adreno (TM) 730"
+	}
+	return "Google Inc. (Intel)", "ANGLE (Intel, Mesa Intel(R) UHD Graphics 620 (KBL GT2), OpenGL 4.6)"
+}
+
+// Apply 在标签页中应用隐身层：覆盖 User-Agent 和 Client Hints，并注入隐身脚本
+// 设置了仿真配置时 User-Agent 和语言由仿真配置决定；User-Agent 覆盖作用于整个标签页，
+// 无法按主机区分，只要有主机启用了隐身层，配置为 off 的主机也会使用覆盖后的 User-Agent 和 Client Hints
+func (c *StealthConfig) Apply(emulationProfile *EmulationProfile) chromedp.Action {
+	return chromedp.ActionFunc(func(ctx context.Context) error {
+		if !c.Enabled() {
+			return nil
+		}
+		fp := stealthFingerprint{DeviceMemory: 8}
+		if emulationProfile != nil {
+			fp.Languages = emulationProfile.Languages()
+			fp.Platform = emulationProfile.Platform
+			fp.Mobile = emulationProfile.Mobile
+		} else if c.UserAgent != "" {
+			if err := emulation.SetUserAgentOverride(c.UserAgent).Do(ctx); err != nil {
+				return err
+			}
+		} else {
+			id, err := resolveBrowserIdentity(ctx)
+			if err != nil {
+				return err
+			}
+			if err := emulation.SetUserAgentOverride(id.UserAgent).
+				WithPlatform(id.Platform).
+				WithUserAgentMetadata(id.Metadata).
+				Do(ctx); err != nil {
+				return err
+			}
+			fp.Platform = id.Platform
+		}
+		if fp.Platform != "" {
+			fp.WebGLVendor, fp.WebGLRenderer = webGLIdentity(fp.Platform)
+		}
+
+		config, err := json.Marshal(struct {
+			Rules       []StealthRule      `json:"rules"`
+			Fingerprint stealthFingerprint `json:"fingerprint"`
+		}{c.Rules, fp})
+		if err != nil {
+			return err
+		}
+		_, err = page.AddScriptToEvaluateOnNewDocument(strings.Replace(stealthJS, "__STEALTH_CONFIG__", string(config), 1)).Do(ctx)
+		return err
+	})
+}
+
+// browserHeaders 通过 SetExtraHTTPHeaders 发送给浏览器的请求头
+// User-Agent 由仿真配置或隐身层覆盖时不再通过额外请求头发送，避免与 navigator.userAgent 不一致
+func (conf *TabConfig) browserHeaders(headers map[string]interface{}) map[string]interface{} {
+	headers = conf.Emulation.FilterHeaders(conf.Session.BrowserHeaders(headers))
+	if conf.Emulation != nil || !conf.Stealth.Enabled() {
+		return headers
+	}
+	result := make(map[string]interface{}, len(headers))
+	for name, value := range headers {
+		if !strings.EqualFold(name, "User-Agent") {
+			result[name] = value
+		}
+	}
+	return result
+}
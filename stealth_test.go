@@ -0,0 +1,53 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseStealthRules(t *testing.T) {
+	tests := []struct {
+		values  []string
+		want    []StealthRule
+		enabled bool
+	}{
+		{nil, []StealthRule{{"*", StealthChrome}}, true},
+		{[]string{"off"}, []StealthRule{{"*", StealthOff}}, false},
+		{[]string{"[*] OFF"}, []StealthRule{{"*", StealthOff}}, false},
+		{
+			[]string{"[*.internal.example.com] off", "[app.internal.example.com] minimal"},
+			[]StealthRule{{"*", StealthChrome}, {"*.internal.example.com", StealthOff}, {"app.internal.example.com", StealthMinimal}},
+			true,
+		},
+		{
+			[]string{"[App.example.com] chrome", "off"},
+			[]StealthRule{{"*", StealthOff}},
+			false,
+		},
+		{
+			[]string{"off", "[app.example.com] minimal"},
+			[]StealthRule{{"*", StealthOff}, {"app.example.com", StealthMinimal}},
+			true,
+		},
+	}
+	for _, tt := range tests {
+		rules, err := parseStealthRules(tt.values)
+		if err != nil || !reflect.DeepEqual(rules, tt.want) {
+			t.Errorf("parseStealthRules(%q) = %v, %v, want %v", tt.values, rules, err, tt.want)
+			continue
+		}
+		if got := (&StealthConfig{Rules: rules}).Enabled(); got != tt.enabled {
+			t.Errorf("parseStealthRules(%q).Enabled() = %v, want %v", tt.values, got, tt.enabled)
+		}
+	}
+
+	for _, values := range [][]string{{"stealthy"}, {"[app.example.com chrome"}, {"[app.example.com]"}} {
+		if _, err := parseStealthRules(values); err == nil {
+			t.Errorf("parseStealthRules(%q) accepted", values)
+		}
+	}
+	var conf *StealthConfig
+	if conf.Enabled() {
+		t.Error("nil config enabled")
+	}
+}
@@ -0,0 +1,324 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	_ "modernc.org/sqlite"
+)
+
+const sqliteSchema = `
+CREATE TABLE IF NOT EXISTS requests (
+	id           INTEGER PRIMARY KEY AUTOINCREMENT,
+	method       TEXT NOT NULL,
+	url          TEXT NOT NULL,
+	pattern      TEXT NOT NULL,
+	headers      TEXT,
+	data         TEXT,
+	content_type TEXT,
+	body_kind    TEXT,
+	body         TEXT,
+	has_file     INTEGER NOT NULL DEFAULT 0,
+	source       TEXT NOT NULL,
+	blocked      INTEGER NOT NULL DEFAULT 0,
+	role         TEXT,
+	profile      TEXT,
+	tokens       TEXT,
+	created_at   DATETIME NOT NULL,
+	UNIQUE(method, url)
+);
+CREATE TABLE IF NOT EXISTS sources (
+	request_id INTEGER NOT NULL REFERENCES requests(id),
+	source     TEXT NOT NULL,
+	created_at DATETIME NOT NULL,
+	UNIQUE(request_id, source)
+);
+CREATE TABLE IF NOT EXISTS responses (
+	id            INTEGER PRIMARY KEY AUTOINCREMENT,
+	url           TEXT NOT NULL,
+	resource_type TEXT,
+	status        INTEGER,
+	mime_type     TEXT,
+	headers       TEXT,
+	created_at    DATETIME NOT NULL
+);
+CREATE TABLE IF NOT EXISTS edges (
+	from_url   TEXT NOT NULL,
+	to_method  TEXT NOT NULL,
+	to_url     TEXT NOT NULL,
+	source     TEXT NOT NULL,
+	created_at DATETIME NOT NULL,
+	UNIQUE(from_url, to_method, to_url, source)
+);
+CREATE TABLE IF NOT EXISTS websocket_messages (
+	url        TEXT NOT NULL,
+	direction  TEXT NOT NULL,
+	opcode     INTEGER NOT NULL,
+	payload    TEXT,
+	truncated  INTEGER NOT NULL DEFAULT 0,
+	created_at DATETIME NOT NULL
+);
+CREATE INDEX IF NOT EXISTS idx_requests_source ON requests(source);
+CREATE INDEX IF NOT EXISTS idx_requests_method ON requests(method);
+CREATE INDEX IF NOT EXISTS idx_responses_url ON responses(url);
+`
+
+// sqliteAddedColumns requests 表后续新增的列，打开旧版本创建的结果文件时补充
+var sqliteAddedColumns = []struct{ name, definition string }{
+	{"blocked", "INTEGER NOT NULL DEFAULT 0"},
+	{"role", "TEXT"},
+	{"profile", "TEXT"},
+	{"tokens", "TEXT"},
+}
+
+// 批量提交参数
+const (
+	sqliteBatchSize     = 200
+	sqliteFlushInterval = 500 * time.Millisecond
+)
+
+// sqliteOp 单条写操作
+type sqliteOp struct {
+	query string
+	args  []interface{}
+}
+
+// SQLiteStore SQLite 持久化后端
+// 写操作由单个协程批量提交，爬取过程中可并发查询（WAL 模式）
+type SQLiteStore struct {
+	mu     sync.RWMutex
+	db     *sql.DB
+	ops    chan sqliteOp
+	done   chan struct{}
+	closed bool
+}
+
+// openSQLiteDB 打开数据库并初始化表结构
+func openSQLiteDB(path string) (*sql.DB, error) {
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		return nil, err
+	}
+	for _, pragma := range []string{"PRAGMA journal_mode=WAL", "PRAGMA busy_timeout=5000", "PRAGMA synchronous=NORMAL"} {
+		if _, err := db.Exec(pragma); err != nil {
+			db.Close()
+			return nil, err
+		}
+	}
+	if _, err := db.Exec(sqliteSchema); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("init schema: %w", err)
+	}
+	if err := migrateSQLiteDB(db); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("migrate schema: %w", err)
+	}
+	return db, nil
+}
+
+// migrateSQLiteDB 为旧版本创建的 requests 表补充新增的列
+func migrateSQLiteDB(db *sql.DB) error {
+	rows, err := db.Query("SELECT name FROM pragma_table_info('requests')")
+	if err != nil {
+		return err
+	}
+	existing := make(map[string]bool)
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			rows.Close()
+			return err
+		}
+		existing[name] = true
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	for _, column := range sqliteAddedColumns {
+		if existing[column.name] {
+			continue
+		}
+		if _, err := db.Exec(fmt.Sprintf("ALTER TABLE requests ADD COLUMN %s %s", column.name, column.definition)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// NewSQLiteStore 创建 SQLite 持久化后端
+func NewSQLiteStore(path string) (*SQLiteStore, error) {
+	db, err := openSQLiteDB(path)
+	if err != nil {
+		return nil, err
+	}
+	store := &SQLiteStore{
+		db:   db,
+		ops:  make(chan sqliteOp, 4096),
+		done: make(chan struct{}),
+	}
+	go store.writer()
+	return store, nil
+}
+
+// writer 写协程，按批次或时间间隔提交事务
+func (s *SQLiteStore) writer() {
+	defer close(s.done)
+	ticker := time.NewTicker(sqliteFlushInterval)
+	defer ticker.Stop()
+
+	batch := make([]sqliteOp, 0, sqliteBatchSize)
+	flush := func() {
+		if len(batch) == 0 {
+			return
+		}
+		if err := s.commit(batch); err != nil {
+			GetGlobalLogger().Error("Failed to commit sqlite batch", err)
+		}
+		batch = batch[:0]
+	}
+
+	for {
+		select {
+		case op, ok := <-s.ops:
+			if !ok {
+				flush()
+				return
+			}
+			batch = append(batch, op)
+			if len(batch) >= sqliteBatchSize {
+				flush()
+			}
+		case <-ticker.C:
+			flush()
+		}
+	}
+}
+
+// commit 在单个事务中执行一批写操作
+func (s *SQLiteStore) commit(batch []sqliteOp) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	for _, op := range batch {
+		if _, err := tx.Exec(op.query, op.args...); err != nil {
+			GetGlobalLogger().Debug(fmt.Sprintf("sqlite exec error: %v", err))
+		}
+	}
+	return tx.Commit()
+}
+
+// enqueue 提交写操作
+func (s *SQLiteStore) enqueue(query string, args ...interface{}) error {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if s.closed {
+		return fmt.Errorf("sqlite store closed")
+	}
+	s.ops <- sqliteOp{query: query, args: args}
+	return nil
+}
+
+// toJSON 序列化为 JSON 字符串，nil 返回 NULL
+func toJSON(v interface{}) interface{} {
+	if v == nil {
+		return nil
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil
+	}
+	return string(data)
+}
+
+// nullString 空字符串写入 NULL
+func nullString(s string) interface{} {
+	if s == "" {
+		return nil
+	}
+	return s
+}
+
+// SaveRequest 写入新请求及其来源
+func (s *SQLiteStore) SaveRequest(req request) error {
+	now := time.Now()
+	var body, bodyKind interface{}
+	if req.Body != nil {
+		body = toJSON(req.Body)
+		bodyKind = req.Body.Kind
+	}
+	hasFile := 0
+	if req.Body.HasFile() {
+		hasFile = 1
+	}
+	blocked := 0
+	if req.Blocked {
+		blocked = 1
+	}
+	var tokens interface{}
+	if len(req.Tokens) > 0 {
+		tokens = toJSON(req.Tokens)
+	}
+	if err := s.enqueue(
+		`INSERT OR IGNORE INTO requests (method, url, pattern, headers, data, content_type, body_kind, body, has_file, source, blocked, role, profile, tokens, created_at)
+		 VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		req.Method, req.URL, urlPattern(req.URL), toJSON(req.Headers), req.Data, req.ContentType, bodyKind, body, hasFile, req.Source,
+		blocked, nullString(req.Role), nullString(req.Profile), tokens, now,
+	); err != nil {
+		return err
+	}
+	return s.SaveSource(req)
+}
+
+// SaveSource 记录请求的发现来源
+func (s *SQLiteStore) SaveSource(req request) error {
+	return s.enqueue(
+		`INSERT OR IGNORE INTO sources (request_id, source, created_at)
+		 SELECT id, ?, ? FROM requests WHERE method = ? AND url = ?`,
+		req.Source, time.Now(), req.Method, req.URL,
+	)
+}
+
+// SaveResponse 写入响应记录
+func (s *SQLiteStore) SaveResponse(resp responseRecord) error {
+	return s.enqueue(
+		`INSERT INTO responses (url, resource_type, status, mime_type, headers, created_at) VALUES (?, ?, ?, ?, ?, ?)`,
+		resp.URL, resp.ResourceType, resp.Status, resp.MimeType, toJSON(resp.Headers), time.Now(),
+	)
+}
+
+// SaveEdge 写入页面到请求的发现关系
+func (s *SQLiteStore) SaveEdge(from string, req request) error {
+	return s.enqueue(
+		`INSERT OR IGNORE INTO edges (from_url, to_method, to_url, source, created_at) VALUES (?, ?, ?, ?, ?)`,
+		from, req.Method, req.URL, req.Source, time.Now(),
+	)
+}
+
+// SaveWebSocketMessage 写入采样的 WebSocket 消息
+func (s *SQLiteStore) SaveWebSocketMessage(rawURL string, msg WebSocketMessage) error {
+	truncated := 0
+	if msg.Truncated {
+		truncated = 1
+	}
+	return s.enqueue(
+		`INSERT INTO websocket_messages (url, direction, opcode, payload, truncated, created_at) VALUES (?, ?, ?, ?, ?, ?)`,
+		rawURL, msg.Direction, msg.Opcode, msg.Payload, truncated, time.Now(),
+	)
+}
+
+// Close 提交剩余写操作并关闭数据库
+func (s *SQLiteStore) Close() error {
+	s.mu.Lock()
+	if !s.closed {
+		s.closed = true
+		close(s.ops)
+	}
+	s.mu.Unlock()
+	<-s.done
+	return s.db.Close()
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"database/sql"
+	"path/filepath"
+	"testing"
+)
+
+func TestSQLiteStoreSaveRequest(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "crawl.db")
+	store, err := NewSQLiteStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req := geneRequest("GET", "https://app.example.com/track.gif", nil, "", "img")
+	req.Blocked = true
+	req.Role = "admin"
+	req.Profile = "mobile"
+	req.Tokens = []TokenRef{{Name: "csrf", In: "query", Kind: "csrf"}}
+	if err := store.SaveRequest(req); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.SaveRequest(geneRequest("GET", "https://app.example.com/", nil, "", "entrance")); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	var blocked int
+	var role, profile, tokens sql.NullString
+	row := db.QueryRow("SELECT blocked, role, profile, tokens FROM requests WHERE url = ?", req.URL)
+	if err := row.Scan(&blocked, &role, &profile, &tokens); err != nil {
+		t.Fatal(err)
+	}
+	if blocked != 1 || role.String != "admin" || profile.String != "mobile" || tokens.String != `[{"name":"csrf","in":"query","kind":"csrf"}]` {
+		t.Errorf("blocked = %d, role = %v, profile = %v, tokens = %v", blocked, role, profile, tokens)
+	}
+	row = db.QueryRow("SELECT blocked, role, profile, tokens FROM requests WHERE source = 'entrance'")
+	if err := row.Scan(&blocked, &role, &profile, &tokens); err != nil {
+		t.Fatal(err)
+	}
+	if blocked != 0 || role.Valid || profile.Valid || tokens.Valid {
+		t.Errorf("entrance: blocked = %d, role = %v, profile = %v, tokens = %v", blocked, role, profile, tokens)
+	}
+}
+
+func TestOpenSQLiteDBMigratesOldSchema(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "old.db")
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.Exec(`CREATE TABLE requests (
+		id INTEGER PRIMARY KEY AUTOINCREMENT, method TEXT NOT NULL, url TEXT NOT NULL, pattern TEXT NOT NULL,
+		headers TEXT, data TEXT, content_type TEXT, body_kind TEXT, body TEXT,
+		has_file INTEGER NOT NULL DEFAULT 0, source TEXT NOT NULL, created_at DATETIME NOT NULL, UNIQUE(method, url))`); err != nil {
+		t.Fatal(err)
+	}
+	db.Close()
+
+	db, err = openSQLiteDB(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	if _, err := db.Exec(`INSERT INTO requests (method, url, pattern, source, blocked, role, profile, tokens, created_at)
+		VALUES ('GET', 'https://app.example.com/', '/', 'entrance', 1, 'admin', 'desktop', '[]', CURRENT_TIMESTAMP)`); err != nil {
+		t.Errorf("insert after migration: %v", err)
+	}
+}
+
+func TestRoleStoreDuplicateSource(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "crawl.db")
+	backend, err := NewSQLiteStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	parent := NewRequestStore()
+	parent.SetBackend(backend)
+	role := NewRoleStore(parent, "admin")
+
+	role.SaveRequest(geneRequest("GET", "https://app.example.com/a", nil, "", "link"))
+	role.SaveRequest(geneRequest("GET", "https://app.example.com/a", nil, "", "json"))
+	if err := backend.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	var count int
+	if err := db.QueryRow("SELECT COUNT(*) FROM sources").Scan(&count); err != nil {
+		t.Fatal(err)
+	}
+	if count != 2 {
+		t.Errorf("sources = %d, want 2", count)
+	}
+}
@@ -0,0 +1,201 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/chromedp/cdproto/fetch"
+	"github.com/chromedp/cdproto/network"
+	"github.com/chromedp/chromedp"
+)
+
+// TLSOptions TLS 相关的命令行参数
+type TLSOptions struct {
+	IgnoreCertErrors bool   // 忽略证书错误
+	CACert           string // 额外信任的 CA 证书（PEM，可包含多个证书）
+	ClientCert       string // 客户端证书（PEM）
+	ClientKey        string // 客户端私钥（PEM），为空时从 ClientCert 文件中读取
+	ClientCertHost   string // 浏览器向哪些主机出示客户端证书，格式同 HeaderRule.Host
+}
+
+// TLSConfig 浏览器和 Go 端 HTTP 客户端共用的 TLS 配置
+type TLSConfig struct {
+	IgnoreCertErrors bool
+	RootCAs          *x509.CertPool
+	CASPKIHashes     []string // CA 证书公钥的 SHA-256（base64），供浏览器 --ignore-certificate-errors-spki-list 使用
+	ClientCert       *tls.Certificate
+	ClientCertHost   string
+}
+
+// tlsSettings 包级别的 TLS 配置，未设置时为 nil
+var tlsSettings *TLSConfig
+
+// loadTLSConfig 加载 TLS 配置，未设置任何选项时返回 nil
+func loadTLSConfig(opts TLSOptions, entranceHost string) (*TLSConfig, error) {
+	if !opts.IgnoreCertErrors && opts.CACert == "" && opts.ClientCert == "" {
+		if opts.ClientKey != "" {
+			return nil, errors.New("-client_key requires -client_cert")
+		}
+		return nil, nil
+	}
+	conf := &TLSConfig{IgnoreCertErrors: opts.IgnoreCertErrors}
+
+	if opts.CACert != "" {
+		content, err := os.ReadFile(opts.CACert)
+		if err != nil {
+			return nil, err
+		}
+		if conf.RootCAs, err = x509.SystemCertPool(); err != nil {
+			conf.RootCAs = x509.NewCertPool()
+		}
+		for block, rest := pem.Decode(content); block != nil; block, rest = pem.Decode(rest) {
+			if block.Type != "CERTIFICATE" {
+				continue
+			}
+			cert, err := x509.ParseCertificate(block.Bytes)
+			if err != nil {
+				return nil, fmt.Errorf("parse %s: %w", opts.CACert, err)
+			}
+			conf.RootCAs.AddCert(cert)
+			hash := sha256.Sum256(cert.RawSubjectPublicKeyInfo)
+			conf.CASPKIHashes = append(conf.CASPKIHashes, base64.StdEncoding.EncodeToString(hash[:]))
+		}
+		if len(conf.CASPKIHashes) == 0 {
+			return nil, fmt.Errorf("%s: no PEM certificate found", opts.CACert)
+		}
+	}
+
+	if opts.ClientCert != "" {
+		keyPath := opts.ClientKey
+		if keyPath == "" {
+			keyPath = opts.ClientCert
+		}
+		cert, err := tls.LoadX509KeyPair(opts.ClientCert, keyPath)
+		if err != nil {
+			return nil, fmt.Errorf("load client certificate: %w", err)
+		}
+		conf.ClientCert = &cert
+		conf.ClientCertHost = strings.ToLower(opts.ClientCertHost)
+		if conf.ClientCertHost == "" {
+			conf.ClientCertHost = entranceHost
+		}
+	} else if opts.ClientKey != "" {
+		return nil, errors.New("-client_key requires -client_cert")
+	}
+	return conf, nil
+}
+
+// GoTLSConfig Go 端 HTTP 客户端的 TLS 配置
+func (c *TLSConfig) GoTLSConfig() *tls.Config {
+	conf := &tls.Config{
+		InsecureSkipVerify: c.IgnoreCertErrors,
+		RootCAs:            c.RootCAs,
+	}
+	if c.ClientCert != nil {
+		conf.Certificates = []tls.Certificate{*c.ClientCert}
+	}
+	return conf
+}
+
+// BrowserOptions 浏览器启动参数
+// 自定义 CA 通过 --ignore-certificate-errors-spki-list 信任，该参数依赖 --user-data-dir（chromedp 默认设置）；
+// 浏览器只比对服务器发送的证书链，服务器未发送 CA 证书时需在 -ca_cert 文件中加入服务器证书本身
+func (c *TLSConfig) BrowserOptions() []chromedp.ExecAllocatorOption {
+	opts := make([]chromedp.ExecAllocatorOption, 0)
+	if c.IgnoreCertErrors {
+		opts = append(opts, chromedp.IgnoreCertErrors)
+	}
+	if len(c.CASPKIHashes) > 0 {
+		opts = append(opts, chromedp.Flag("ignore-certificate-errors-spki-list", strings.Join(c.CASPKIHashes, ",")))
+	}
+	return opts
+}
+
+// NeedsClientCert 判断浏览器请求是否需要出示客户端证书
+func (c *TLSConfig) NeedsClientCert(rawURL string) bool {
+	if c == nil || c.ClientCert == nil || !strings.HasPrefix(strings.ToLower(rawURL), "https://") {
+		return false
+	}
+	return hostMatch(c.ClientCertHost, hostOf(rawURL))
+}
+
+// HasClientCert 是否配置了客户端证书
+func (c *TLSConfig) HasClientCert() bool {
+	return c != nil && c.ClientCert != nil
+}
+
+// configureTLS 设置 TLS 配置，Go 端所有 HTTP 客户端共享 baseTransport，须在发出请求前调用
+func configureTLS(c *TLSConfig) {
+	tlsSettings = c
+	if c != nil {
+		baseTransport.TLSClientConfig = c.GoTLSConfig()
+	}
+}
+
+// fulfillWithClientCert 浏览器无法通过 CDP 出示客户端证书，改由 Go 端 HTTP 客户端发送请求，再将响应交给浏览器
+// 会话 cookie 由 cookie jar 携带，响应中的 Set-Cookie 同时更新会话和浏览器
+func fulfillWithClientCert(ctx context.Context, ev *fetch.EventRequestPaused, client *http.Client) error {
+	req, err := newGoRequest(client, ev.Request.Method, ev.Request.URL, ev.Request.Headers)
+	if err != nil {
+		return err
+	}
+	if ev.Request.HasPostData {
+		var body bytes.Buffer
+		for _, entry := range ev.Request.PostDataEntries {
+			data, err := base64.StdEncoding.DecodeString(entry.Bytes)
+			if err != nil {
+				return err
+			}
+			body.Write(data)
+		}
+		// 请求体较大或为流式上传时事件中不包含请求体，需要单独获取
+		if len(ev.Request.PostDataEntries) == 0 && ev.NetworkID != "" {
+			data, err := network.GetRequestPostData(ev.NetworkID).Do(ctx)
+			if err != nil {
+				GetGlobalLogger().ErrorWithURL("Failed to get request body for client certificate request", ev.Request.URL, err)
+				return fetch.FailRequest(ev.RequestID, network.ErrorReasonFailed).Do(ctx)
+			}
+			body.WriteString(data)
+		}
+		req.Body = io.NopCloser(&body)
+		req.ContentLength = int64(body.Len())
+	}
+	// 由传输层协商压缩并解压响应体，避免转交给浏览器的响应体与 Content-Encoding 不一致
+	req.Header.Del("Accept-Encoding")
+
+	resp, err := client.Do(req.WithContext(ctx))
+	if err != nil {
+		GetGlobalLogger().ErrorWithURL("Failed to send request with client certificate", ev.Request.URL, err)
+		return fetch.FailRequest(ev.RequestID, network.ErrorReasonFailed).Do(ctx)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fetch.FailRequest(ev.RequestID, network.ErrorReasonFailed).Do(ctx)
+	}
+
+	headers := make([]*fetch.HeaderEntry, 0, len(resp.Header))
+	for name, values := range resp.Header {
+		if strings.EqualFold(name, "Content-Length") || strings.EqualFold(name, "Content-Encoding") {
+			continue
+		}
+		for _, value := range values {
+			headers = append(headers, &fetch.HeaderEntry{Name: name, Value: value})
+		}
+	}
+	return fetch.FulfillRequest(ev.RequestID, int64(resp.StatusCode)).
+		WithResponseHeaders(headers).
+		WithBody(base64.StdEncoding.EncodeToString(body)).
+		Do(ctx)
+}
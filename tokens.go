@@ -0,0 +1,256 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/chromedp/cdproto/network"
+)
+
+// 动态令牌来源类型
+const (
+	TokenFromSetCookie      = "set-cookie"      // 响应的 Set-Cookie
+	TokenFromMeta           = "meta"            // 页面 meta 标签
+	TokenFromForm           = "form"            // 表单隐藏字段
+	TokenFromResponseHeader = "response-header" // 响应头
+)
+
+// csrfNamePattern 反 CSRF 令牌名（与 collectTokensJS 中的正则保持一致）
+var csrfNamePattern = regexp.MustCompile(`(?i)csrf|xsrf|anti.?forgery|authenticity_token|verificationtoken|nonce|^_token$`)
+
+// TokenSource 令牌的获取位置，重放前可据此刷新令牌
+type TokenSource struct {
+	Type     string `json:"type"`
+	URL      string `json:"url"`                // 返回该令牌的页面或响应
+	Selector string `json:"selector,omitempty"` // meta 和表单令牌的 CSS 选择器
+	Header   string `json:"header,omitempty"`   // 响应头令牌的名称
+}
+
+// TokenRef 请求中的动态令牌
+type TokenRef struct {
+	Name   string       `json:"name"`
+	In     string       `json:"in"`   // cookie、header、query 或请求体类型（form、json、multipart 等）
+	Kind   string       `json:"kind"` // csrf 或 cookie（应用通过 Set-Cookie 轮换的 cookie）
+	Source *TokenSource `json:"source,omitempty"`
+}
+
+// tokenField 页面脚本上报的令牌字段
+type tokenField struct {
+	Type     string `json:"type"`
+	Name     string `json:"name"`
+	Selector string `json:"selector"`
+}
+
+// tokenTracker 记录会话中动态令牌的最新来源
+type tokenTracker struct {
+	mu      sync.RWMutex
+	cookies map[string]TokenSource // cookie 名 -> 最近一次设置它的响应
+	fields  map[string]TokenSource // 归一化令牌名 -> 页面或响应头来源
+}
+
+// newTokenTracker 创建令牌追踪器
+func newTokenTracker() *tokenTracker {
+	return &tokenTracker{
+		cookies: make(map[string]TokenSource),
+		fields:  make(map[string]TokenSource),
+	}
+}
+
+// normalizeTokenName 归一化令牌名，使 X-CSRF-Token、csrf-token 和 csrf_token 可以互相匹配
+func normalizeTokenName(name string) string {
+	var sb strings.Builder
+	for _, r := range strings.ToLower(name) {
+		if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') {
+			sb.WriteRune(r)
+		}
+	}
+	normalized := sb.String()
+	if strings.HasPrefix(strings.ToLower(name), "x-") {
+		normalized = strings.TrimPrefix(normalized, "x")
+	}
+	return normalized
+}
+
+// TrackResponse 记录响应中的 Set-Cookie 和令牌响应头，并将 cookie 更新同步到会话
+func (s *Session) TrackResponse(rawURL string, headers network.Headers) {
+	if s == nil {
+		return
+	}
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return
+	}
+	for name, value := range headers {
+		strValue := fmt.Sprint(value)
+		if strings.EqualFold(name, "Set-Cookie") {
+			// 同名响应头以换行符连接
+			cookies := make([]*http.Cookie, 0)
+			for _, line := range strings.Split(strValue, "\n") {
+				if cookie, err := http.ParseSetCookie(line); err == nil {
+					cookies = append(cookies, cookie)
+				}
+			}
+			s.Jar().SetCookies(u, cookies)
+			s.tokens.mu.Lock()
+			for _, cookie := range cookies {
+				s.tokens.cookies[cookie.Name] = TokenSource{Type: TokenFromSetCookie, URL: rawURL}
+			}
+			s.tokens.mu.Unlock()
+			continue
+		}
+		if csrfNamePattern.MatchString(name) {
+			s.tokens.mu.Lock()
+			s.tokens.fields[normalizeTokenName(name)] = TokenSource{Type: TokenFromResponseHeader, URL: rawURL, Header: name}
+			s.tokens.mu.Unlock()
+		}
+	}
+}
+
+// RefreshHeaders 按会话当前的 cookie 返回请求头副本：应用通过 Set-Cookie 设置过的 cookie 使用最新值，已删除的从 Cookie 头中去掉
+func (s *Session) RefreshHeaders(rawURL string, headers map[string]interface{}) map[string]interface{} {
+	if s == nil {
+		return headers
+	}
+	s.tokens.mu.RLock()
+	managed := make(map[string]bool, len(s.tokens.cookies))
+	for name := range s.tokens.cookies {
+		managed[name] = true
+	}
+	s.tokens.mu.RUnlock()
+
+	if len(managed) > 0 {
+		copied := make(map[string]interface{}, len(headers))
+		for name, value := range headers {
+			if strValue, ok := value.(string); ok && strings.EqualFold(name, "Cookie") {
+				pairs := make([]string, 0)
+				for _, pair := range strings.Split(strValue, ";") {
+					pair = strings.TrimSpace(pair)
+					cookieName, _, _ := strings.Cut(pair, "=")
+					if cookieName != "" && !managed[cookieName] {
+						pairs = append(pairs, pair)
+					}
+				}
+				if len(pairs) == 0 {
+					continue
+				}
+				value = strings.Join(pairs, "; ")
+			}
+			copied[name] = value
+		}
+		headers = copied
+	}
+	return s.ApplyHeaders(rawURL, headers)
+}
+
+// RefreshSessionHeaders 按会话的最新状态更新已保存请求的 Cookie 头和令牌标注，输出结果前调用
+// 多角色爬取时按请求所属角色的会话更新
+func (rs *RequestStore) RefreshSessionHeaders() {
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+	for i := range rs.requests {
+		req := &rs.requests[i]
+		session := rs.session
+		if req.Role != "" && rs.roleSessions[req.Role] != nil {
+			session = rs.roleSessions[req.Role]
+		}
+		if session == nil {
+			continue
+		}
+		req.Headers = session.RefreshHeaders(req.URL, req.Headers)
+		req.Tokens = session.AnnotateTokens(*req)
+	}
+}
+
+// RecordTokenFields 记录页面中的 meta 和表单令牌
+func (s *Session) RecordTokenFields(pageURL string, fields []tokenField) {
+	if s == nil {
+		return
+	}
+	s.tokens.mu.Lock()
+	defer s.tokens.mu.Unlock()
+	for _, field := range fields {
+		if field.Type != TokenFromMeta && field.Type != TokenFromForm {
+			continue
+		}
+		s.tokens.fields[normalizeTokenName(field.Name)] = TokenSource{Type: field.Type, URL: pageURL, Selector: field.Selector}
+	}
+}
+
+// csrfSource 查找令牌来源：同名的页面或响应头令牌，其次是同名 cookie（如 XSRF-TOKEN 对应 X-XSRF-TOKEN）
+func (t *tokenTracker) csrfSource(name string) *TokenSource {
+	normalized := normalizeTokenName(name)
+	if source, ok := t.fields[normalized]; ok {
+		return &source
+	}
+	for cookieName, source := range t.cookies {
+		if normalizeTokenName(cookieName) == normalized {
+			return &source
+		}
+	}
+	return nil
+}
+
+// AnnotateTokens 标注请求中的动态令牌：应用轮换的 cookie，以及请求头和参数中的反 CSRF 令牌
+func (s *Session) AnnotateTokens(req request) []TokenRef {
+	if s == nil {
+		return nil
+	}
+	s.tokens.mu.RLock()
+	defer s.tokens.mu.RUnlock()
+
+	tokens := make([]TokenRef, 0)
+	for name, value := range req.Headers {
+		strValue, _ := value.(string)
+		if strings.EqualFold(name, "Cookie") {
+			for _, pair := range strings.Split(strValue, ";") {
+				cookieName, _, _ := strings.Cut(strings.TrimSpace(pair), "=")
+				source, ok := s.tokens.cookies[cookieName]
+				if !ok && !csrfNamePattern.MatchString(cookieName) {
+					continue
+				}
+				ref := TokenRef{Name: cookieName, In: "cookie", Kind: "cookie"}
+				if csrfNamePattern.MatchString(cookieName) {
+					ref.Kind = "csrf"
+				}
+				if ok {
+					ref.Source = &source
+				}
+				tokens = append(tokens, ref)
+			}
+			continue
+		}
+		if csrfNamePattern.MatchString(name) {
+			tokens = append(tokens, TokenRef{Name: name, In: "header", Kind: "csrf", Source: s.tokens.csrfSource(name)})
+		}
+	}
+
+	if u, err := url.Parse(req.URL); err == nil {
+		for name := range u.Query() {
+			if csrfNamePattern.MatchString(name) {
+				tokens = append(tokens, TokenRef{Name: name, In: "query", Kind: "csrf", Source: s.tokens.csrfSource(name)})
+			}
+		}
+	}
+	if req.Body != nil {
+		for _, param := range req.Body.Params {
+			if csrfNamePattern.MatchString(param.Name) {
+				tokens = append(tokens, TokenRef{Name: param.Name, In: req.Body.Kind, Kind: "csrf", Source: s.tokens.csrfSource(param.Name)})
+			}
+		}
+	}
+	if len(tokens) == 0 {
+		return nil
+	}
+	sort.Slice(tokens, func(i, j int) bool {
+		if tokens[i].In != tokens[j].In {
+			return tokens[i].In < tokens[j].In
+		}
+		return tokens[i].Name < tokens[j].Name
+	})
+	return tokens
+}
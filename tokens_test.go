@@ -0,0 +1,30 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/chromedp/cdproto/network"
+)
+
+func TestRefreshSessionHeaders(t *testing.T) {
+	session := NewSession()
+	session.TrackResponse("https://app.example.com/login", network.Headers{"Set-Cookie": "sid=old; Path=/\ncsrftoken=t1; Path=/"})
+
+	store := NewRequestStore()
+	store.SetSession(session)
+	rawURL := "https://app.example.com/account"
+	store.requests = append(store.requests, request{Method: "GET", URL: rawURL, Headers: session.ApplyHeaders(rawURL, map[string]interface{}{"Cookie": "theme=dark"})})
+
+	// 应用轮换 sid，删除 csrftoken
+	session.TrackResponse("https://app.example.com/account", network.Headers{"Set-Cookie": "sid=new; Path=/\ncsrftoken=; Path=/; Max-Age=0"})
+	store.RefreshSessionHeaders()
+
+	req := store.GetRequests()[0]
+	want := "theme=dark; sid=new"
+	if got := getHeader(req.Headers, "Cookie"); got != want {
+		t.Errorf("Cookie = %q, want %q", got, want)
+	}
+	if len(req.Tokens) != 1 || req.Tokens[0].Name != "sid" || req.Tokens[0].Source == nil || req.Tokens[0].Source.URL != "https://app.example.com/account" {
+		t.Errorf("Tokens = %+v, want sid from /account", req.Tokens)
+	}
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"crypto/hmac"
+	"crypto/sha1"
+	"encoding/base32"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// TOTP 参数（RFC 6238 默认值，与常见验证器应用一致）
+const (
+	totpPeriod = 30
+	totpDigits = 6
+	// 剩余有效期不足时等待下一个周期，避免提交时验证码已过期
+	totpMinRemaining = 5 * time.Second
+)
+
+// totpPlaceholder 登录脚本中的一次性验证码占位符
+const totpPlaceholder = "{{totp}}"
+
+// decodeTOTPSecret 解码 base32 格式的 TOTP 密钥，忽略空格、大小写和填充
+func decodeTOTPSecret(secret string) ([]byte, error) {
+	cleaned := strings.ToUpper(strings.ReplaceAll(strings.TrimSpace(secret), " ", ""))
+	cleaned = strings.TrimRight(cleaned, "=")
+	if cleaned == "" {
+		return nil, errors.New("empty totp secret")
+	}
+	key, err := base32.StdEncoding.WithPadding(base32.NoPadding).DecodeString(cleaned)
+	if err != nil {
+		return nil, fmt.Errorf("invalid totp secret: %w", err)
+	}
+	return key, nil
+}
+
+// generateTOTP 按 RFC 6238 计算指定时间的验证码（HMAC-SHA1）
+func generateTOTP(key []byte, t time.Time) string {
+	var counter [8]byte
+	binary.BigEndian.PutUint64(counter[:], uint64(t.Unix()/totpPeriod))
+
+	mac := hmac.New(sha1.New, key)
+	mac.Write(counter[:])
+	sum := mac.Sum(nil)
+
+	// 动态截断（RFC 4226 5.3）
+	offset := sum[len(sum)-1] & 0x0f
+	code := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+
+	mod := uint32(1)
+	for i := 0; i < totpDigits; i++ {
+		mod *= 10
+	}
+	return fmt.Sprintf("%0*d", totpDigits, code%mod)
+}
+
+// currentTOTP 获取当前验证码，剩余有效期过短时返回下一周期的验证码所需等待时长
+func currentTOTP(key []byte, now time.Time) (string, time.Duration) {
+	elapsed := time.Duration(now.Unix()%totpPeriod)*time.Second + time.Duration(now.Nanosecond())
+	remaining := totpPeriod*time.Second - elapsed
+	if remaining < totpMinRemaining {
+		return generateTOTP(key, now.Add(remaining)), remaining
+	}
+	return generateTOTP(key, now), 0
+}
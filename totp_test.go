@@ -0,0 +1,71 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+// RFC 6238 附录 B 的 SHA1 测试向量，验证码取 8 位结果的后 6 位
+func TestGenerateTOTP(t *testing.T) {
+	key := []byte("12345678901234567890")
+	tests := []struct {
+		unix int64
+		want string
+	}{
+		{59, "287082"},
+		{1111111109, "081804"},
+		{1111111111, "050471"},
+		{1234567890, "005924"},
+		{2000000000, "279037"},
+		{20000000000, "353130"},
+	}
+	for _, tt := range tests {
+		if got := generateTOTP(key, time.Unix(tt.unix, 0)); got != tt.want {
+			t.Errorf("generateTOTP(%d) = %s, want %s", tt.unix, got, tt.want)
+		}
+	}
+}
+
+func TestDecodeTOTPSecret(t *testing.T) {
+	tests := []struct {
+		secret  string
+		want    string
+		wantErr bool
+	}{
+		{"GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ", "12345678901234567890", false},
+		{"gezd gnbv gy3t qojq gezd gnbv gy3t qojq", "12345678901234567890", false},
+		{"MFRGG===", "abc", false},
+		{"", "", true},
+		{"not-base32!", "", true},
+	}
+	for _, tt := range tests {
+		key, err := decodeTOTPSecret(tt.secret)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("decodeTOTPSecret(%q) error = %v, wantErr %v", tt.secret, err, tt.wantErr)
+			continue
+		}
+		if string(key) != tt.want {
+			t.Errorf("decodeTOTPSecret(%q) = %q, want %q", tt.secret, key, tt.want)
+		}
+	}
+}
+
+func TestCurrentTOTP(t *testing.T) {
+	key := []byte("12345678901234567890")
+	tests := []struct {
+		now      time.Time
+		wantCode string
+		wantWait time.Duration
+	}{
+		// 周期内剩余 29 秒，直接使用当前验证码
+		{time.Unix(1111111111-1111111111%30+1, 0), generateTOTP(key, time.Unix(1111111111, 0)), 0},
+		// 剩余 1 秒，等待并使用下一周期的验证码
+		{time.Unix(59, 0), generateTOTP(key, time.Unix(60, 0)), time.Second},
+	}
+	for _, tt := range tests {
+		code, wait := currentTOTP(key, tt.now)
+		if code != tt.wantCode || wait != tt.wantWait {
+			t.Errorf("currentTOTP(%v) = %s, %v, want %s, %v", tt.now.Unix(), code, wait, tt.wantCode, tt.wantWait)
+		}
+	}
+}
@@ -4,6 +4,7 @@ import (
 	b64 "encoding/base64"
 	"errors"
 	"fmt"
+	"net/http"
 	"net/url"
 	"os"
 	"strings"
@@ -39,6 +40,39 @@ type RequestStore struct {
 	mu       sync.RWMutex
 	requests []request
 	seen     map[string]bool // key: Method+URL
+	hidden   map[string]bool // 表单隐藏字段：端点模式 + 字段名
+	backend  StoreBackend    // 可选的持久化后端
+	session  *Session        // 会话，用于记录请求实际携带的 cookie
+	parent   *RequestStore   // 多角色爬取时汇总所有角色请求的存储
+	role     string
+	profile  string          // 当前的仿真配置名
+
+	roleSessions map[string]*Session // 多角色爬取时各角色的会话，用于输出前更新请求的 cookie
+
+	webSocketMu sync.Mutex
+	webSockets  map[string]*WebSocketInfo // 已保存的 WebSocket 端点：归一化 URL -> 端点记录
+
+	postMessages     map[string]*PostMessageEntry // 页面的 postMessage 清单：归一化页面 URL -> 清单
+	postMessagePages []string                     // 页面发现顺序
+}
+
+// StoreBackend 请求持久化后端，RequestStore 在内存去重后写入
+type StoreBackend interface {
+	SaveRequest(req request) error
+	SaveSource(req request) error // 已存在的请求被其它来源再次发现
+	SaveResponse(resp responseRecord) error
+	SaveEdge(from string, req request) error
+	SaveWebSocketMessage(rawURL string, msg WebSocketMessage) error
+	Close() error
+}
+
+// responseRecord 响应记录
+type responseRecord struct {
+	URL          string
+	ResourceType string
+	Status       int64
+	MimeType     string
+	Headers      map[string]interface{}
 }
 
 // NewRequestStore 创建新的请求存储
@@ -46,13 +80,39 @@ func NewRequestStore() *RequestStore {
 	return &RequestStore{
 		requests: make([]request, 0),
 		seen:     make(map[string]bool),
+		hidden:   make(map[string]bool),
+	}
+}
+
+// NewRoleStore 创建角色请求存储，新请求标记角色后同步保存到 parent
+func NewRoleStore(parent *RequestStore, role string) *RequestStore {
+	rs := NewRequestStore()
+	rs.parent = parent
+	rs.role = role
+	return rs
+}
+
+// getBackend 获取持久化后端，角色存储使用 parent 的后端
+func (rs *RequestStore) getBackend() StoreBackend {
+	rs.mu.RLock()
+	defer rs.mu.RUnlock()
+	if rs.parent != nil {
+		return rs.parent.getBackend()
 	}
+	return rs.backend
 }
 
 // BrowserConfig 浏览器配置
 type BrowserConfig struct {
 	Headless     bool
 	ChromiumPath string
+	Proxy        *ProxyConfig // 上游代理
+	TLS          *TLSConfig   // TLS 配置
+	RemoteURL    string       // 已运行浏览器的远程调试地址，设置后不再启动新浏览器
+	UserDataDir  string       // 复用的浏览器配置文件目录
+	Extensions   []string     // 解压后的扩展目录（绝对路径）
+	ExtraFlags   []string     // 额外的浏览器启动参数
+	Resolve      []ResolveRule // 主机解析覆盖规则
 }
 
 // TabConfig 标签页配置
@@ -63,15 +123,32 @@ type TabConfig struct {
 	TriggerEventInterval  int
 	TabConcurrentQuantity int
 	Headers               map[string]interface{}
+	Session               *Session     // 登录会话，所有标签页共享
+	HTTPClient            *http.Client // Go 端 HTTP 客户端，使用 Session 的 cookie 和凭据，多角色爬取时每个角色独立
+	LoginScript           *LoginScript // 登录脚本，为空则不登录
+	Role                  string       // 角色名，多角色爬取时每个角色使用独立的浏览器上下文
+	SessionMonitor        *SessionMonitor // 会话监控，为空则不检测
+	Isolation             string          // 浏览器上下文隔离方式：shared、role 或 tab
+	SeedSession           bool            // 新标签页是否写入会话 cookie 和 Web Storage
+	Policy                *ResourcePolicy // 请求拦截策略，为空则使用默认策略
+	Emulation             *EmulationProfile // 设备仿真配置，为空则不仿真
+	Stealth               *StealthConfig    // 隐身层配置，为空则不修改浏览器指纹
 }
 
 // request HTTP 请求结构体
 type request struct {
-	Method  string                 `json:"method"`
-	URL     string                 `json:"url"`
-	Headers map[string]interface{} `json:"headers"`
-	Data    string                 `json:"data"` // base64 编码
-	Source  string                 `json:"source"`
+	Method      string                 `json:"method"`
+	URL         string                 `json:"url"`
+	Headers     map[string]interface{} `json:"headers"`
+	Data        string                 `json:"data"` // base64 编码
+	ContentType string                 `json:"content_type,omitempty"`
+	Body        *RequestBody           `json:"body,omitempty"` // 按 Content-Type 解析后的请求体
+	Source      string                 `json:"source"`
+	Role        string                 `json:"role,omitempty"` // 多角色爬取时首个发现该请求的角色
+	Tokens      []TokenRef             `json:"tokens,omitempty"` // 请求中的动态令牌及其来源
+	Blocked     bool                   `json:"blocked,omitempty"` // 请求被拦截未发出，仅记录
+	Profile     string                 `json:"profile,omitempty"` // 多个仿真配置时首个发现该请求的配置
+	WebSocket   *WebSocketInfo         `json:"websocket,omitempty"` // WebSocket 端点的握手响应和消息采样
 }
 
 func getFileExtFromUrl(rawUrl string) (string, error) {
@@ -86,11 +163,22 @@ func getFileExtFromUrl(rawUrl string) (string, error) {
 	return u.Path[pos:len(u.Path)], nil
 }
 
+// httpScheme WebSocket 协议对应的 HTTP 协议，用于与入口 URL 比较
+func httpScheme(scheme string) string {
+	switch scheme {
+	case "ws":
+		return "http"
+	case "wss":
+		return "https"
+	}
+	return scheme
+}
+
 func checkReq(req request) bool {
 	newurl := strings.ToLower(req.URL)
 
-	// 过滤非 HTTP 请求
-	if !strings.HasPrefix(newurl, "http") {
+	// 过滤非 HTTP 和 WebSocket 请求
+	if !strings.HasPrefix(newurl, "http") && !strings.HasPrefix(newurl, "ws") {
 		return false
 	}
 
@@ -102,7 +190,7 @@ func checkReq(req request) bool {
 	// 协议、域名必须和初始 URL 相同（使用缓存的 entranceURL）
 	entU := getEntranceURL()
 	reqU, _ := url.Parse(newurl)
-	if entU.Scheme != reqU.Scheme || entU.Host != reqU.Host {
+	if entU.Scheme != httpScheme(reqU.Scheme) || entU.Host != reqU.Host {
 		return false
 	}
 
@@ -119,12 +207,15 @@ func checkReq(req request) bool {
 }
 
 func geneRequest(method string, url string, headers map[string]interface{}, data string, source string) request {
+	contentType := getHeader(headers, "Content-Type")
 	return request{
-		Method:  method,
-		URL:     url,
-		Headers: headers,
-		Data:    b64.StdEncoding.EncodeToString([]byte(data)),
-		Source:  source,
+		Method:      method,
+		URL:         url,
+		Headers:     headers,
+		Data:        b64.StdEncoding.EncodeToString([]byte(data)),
+		ContentType: contentType,
+		Body:        decodeBody(contentType, []byte(data)),
+		Source:      source,
 	}
 }
 
@@ -146,23 +237,124 @@ func (rs *RequestStore) SaveRequest(req request) bool {
 	key := req.Method + req.URL
 	
 	rs.mu.Lock()
-	defer rs.mu.Unlock()
 	
 	// 检查是否达到上限
 	if len(rs.requests) >= MaxStoredRequests {
+		rs.mu.Unlock()
 		GetGlobalLogger().Warn(fmt.Sprintf("Request limit reached (%d), ignoring new requests", MaxStoredRequests))
 		return false
 	}
 	
-	if !rs.seen[key] {
-		rs.seen[key] = true
-		rs.requests = append(rs.requests, req)
-		// 记录到结构化日志
-		GetGlobalLogger().Info(fmt.Sprintf("[%s] %s (source: %s)", req.Method, req.URL, req.Source))
+	// 父存储和持久化后端在释放锁后调用，避免持锁时阻塞在后端的写入队列上
+	backend := rs.backend
+	if rs.seen[key] {
+		rs.mu.Unlock()
+		// 角色存储没有自己的后端，重复发现的来源写入父存储的后端
+		if backend := rs.getBackend(); backend != nil {
+			_ = backend.SaveSource(req)
+		}
+		return false
+	}
+	
+	rs.seen[key] = true
+	// 浏览器上报的请求头不含 cookie，按作用域补充会话 cookie，并标注动态令牌
+	if rs.session != nil {
+		req.Headers = rs.session.ApplyHeaders(req.URL, req.Headers)
+		req.Tokens = rs.session.AnnotateTokens(req)
+	}
+	if req.Profile == "" {
+		req.Profile = rs.profile
+	}
+	rs.requests = append(rs.requests, req)
+	parent, role := rs.parent, rs.role
+	rs.mu.Unlock()
+	
+	if parent != nil {
+		// 由 parent 记录日志和持久化
+		req.Role = role
+		parent.SaveRequest(req)
 		return true
 	}
+	// 记录到结构化日志
+	GetGlobalLogger().Info(fmt.Sprintf("[%s] %s (source: %s)", req.Method, req.URL, req.Source))
+	if backend != nil {
+		if err := backend.SaveRequest(req); err != nil {
+			GetGlobalLogger().ErrorWithURL("Failed to persist request", req.URL, err)
+		}
+	}
+	return true
+}
+
+// SaveRequestFrom 保存请求，并记录从父页面到该请求的发现关系
+func (rs *RequestStore) SaveRequestFrom(parent string, req request) bool {
+	saved := rs.SaveRequest(req)
 	
-	return false
+	backend := rs.getBackend()
+	if backend != nil && parent != "" {
+		if normalizedURL, err := normalizeURL(req.URL); err == nil && checkReq(request{URL: normalizedURL}) {
+			req.URL = normalizedURL
+			_ = backend.SaveEdge(parent, req)
+		}
+	}
+	return saved
+}
+
+// SetBackend 设置持久化后端
+func (rs *RequestStore) SetBackend(backend StoreBackend) {
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+	rs.backend = backend
+}
+
+// SetSession 设置会话，角色存储同时登记到 parent
+func (rs *RequestStore) SetSession(session *Session) {
+	rs.mu.Lock()
+	rs.session = session
+	parent, role := rs.parent, rs.role
+	rs.mu.Unlock()
+
+	if parent != nil {
+		parent.mu.Lock()
+		defer parent.mu.Unlock()
+		if parent.roleSessions == nil {
+			parent.roleSessions = make(map[string]*Session)
+		}
+		parent.roleSessions[role] = session
+	}
+}
+
+// SetProfile 设置当前的仿真配置名，之后新发现的请求标记该配置
+func (rs *RequestStore) SetProfile(profile string) {
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+	rs.profile = profile
+}
+
+// SaveResponse 记录响应（仅在设置了持久化后端时生效），URL 与请求一样规范化，便于按 URL 关联
+func (rs *RequestStore) SaveResponse(resp responseRecord) {
+	backend := rs.getBackend()
+	if backend == nil {
+		return
+	}
+	normalizedURL, err := normalizeURL(resp.URL)
+	if err != nil {
+		return
+	}
+	resp.URL = normalizedURL
+	_ = backend.SaveResponse(resp)
+}
+
+// CloseBackend 关闭持久化后端，确保数据落盘
+func (rs *RequestStore) CloseBackend() {
+	rs.mu.Lock()
+	backend := rs.backend
+	rs.backend = nil
+	rs.mu.Unlock()
+	if backend != nil {
+		if err := backend.Close(); err != nil {
+			GetGlobalLogger().Error("Failed to close store backend", err)
+		}
+	}
 }
 
 // GetRequestCount 获取请求数量（并发安全）
@@ -182,6 +374,39 @@ func (rs *RequestStore) GetRequests() []request {
 	return result
 }
 
+// hiddenParamKey 隐藏字段的标记键：表单提交的端点模式 + 字段名
+func hiddenParamKey(action, name string) string {
+	return urlPattern(action) + "\x00" + name
+}
+
+// MarkHiddenParams 记录表单中的隐藏字段，按表单提交的端点区分
+func (rs *RequestStore) MarkHiddenParams(action string, names []string) {
+	if normalizedURL, err := normalizeURL(action); err == nil {
+		action = normalizedURL
+	}
+	rs.mu.Lock()
+	for _, name := range names {
+		rs.hidden[hiddenParamKey(action, name)] = true
+	}
+	parent := rs.parent
+	rs.mu.Unlock()
+
+	if parent != nil {
+		parent.MarkHiddenParams(action, names)
+	}
+}
+
+// GetHiddenParams 获取隐藏字段标记集合的副本（并发安全），键由 hiddenParamKey 生成
+func (rs *RequestStore) GetHiddenParams() map[string]bool {
+	rs.mu.RLock()
+	defer rs.mu.RUnlock()
+	result := make(map[string]bool, len(rs.hidden))
+	for key := range rs.hidden {
+		result[key] = true
+	}
+	return result
+}
+
 // normalizeURL 规范化 URL
 func normalizeURL(rawURL string) (string, error) {
 	u, err := url.Parse(rawURL)
@@ -194,9 +419,9 @@ func normalizeURL(rawURL string) (string, error) {
 	u.Host = strings.ToLower(u.Host)
 	
 	// 移除默认端口
-	if u.Scheme == "http" && strings.HasSuffix(u.Host, ":80") {
+	if (u.Scheme == "http" || u.Scheme == "ws") && strings.HasSuffix(u.Host, ":80") {
 		u.Host = strings.TrimSuffix(u.Host, ":80")
-	} else if u.Scheme == "https" && strings.HasSuffix(u.Host, ":443") {
+	} else if (u.Scheme == "https" || u.Scheme == "wss") && strings.HasSuffix(u.Host, ":443") {
 		u.Host = strings.TrimSuffix(u.Host, ":443")
 	}
 	
@@ -0,0 +1,210 @@
+package main
+
+import (
+	"encoding/json"
+	"sync"
+
+	"github.com/chromedp/cdproto/network"
+)
+
+// WebSocket 消息采样
+const (
+	webSocketMaxPayload = 4096 // 单条消息最多保留的字节数
+)
+
+// webSocketSampleLimit 每个 WebSocket 端点最多采样的不重复消息数，0 表示不采样
+var webSocketSampleLimit = 20
+
+// WebSocketMessage WebSocket 消息
+type WebSocketMessage struct {
+	Direction string `json:"direction"` // sent 或 received
+	Opcode    int    `json:"opcode"`    // 1 文本，2 二进制
+	Payload   string `json:"payload"`   // 文本消息为原文，二进制消息为 base64 编码
+	Truncated bool   `json:"truncated,omitempty"`
+}
+
+// webSocketRecord WebSocket 端点的输出字段
+type webSocketRecord struct {
+	Status          int64                  `json:"status,omitempty"` // 握手响应状态码
+	ResponseHeaders map[string]interface{} `json:"response_headers,omitempty"`
+	Pages           []string               `json:"pages"`         // 建立连接的页面
+	MessageCount    int                    `json:"message_count"` // 收发的消息总数，包括未采样的
+	Messages        []WebSocketMessage     `json:"messages,omitempty"`
+}
+
+// WebSocketInfo WebSocket 端点信息，同一端点在不同页面和标签页中的连接合并记录
+type WebSocketInfo struct {
+	webSocketRecord
+	mu   sync.Mutex
+	seen map[string]bool // 已采样的消息，重复的消息（如心跳）只保留一条
+}
+
+// MarshalJSON 序列化时持有锁，输出结果时标签页可能仍在采样消息
+func (w *WebSocketInfo) MarshalJSON() ([]byte, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return json.Marshal(w.webSocketRecord)
+}
+
+// addPage 记录建立连接的页面
+func (w *WebSocketInfo) addPage(page string) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	for _, p := range w.Pages {
+		if p == page {
+			return
+		}
+	}
+	w.Pages = append(w.Pages, page)
+}
+
+// setHandshake 记录握手响应
+func (w *WebSocketInfo) setHandshake(status int64, headers map[string]interface{}) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.Status = status
+	w.ResponseHeaders = headers
+}
+
+// addMessage 采样消息，返回是否为新采样的消息
+func (w *WebSocketInfo) addMessage(msg WebSocketMessage) bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.MessageCount++
+	if len(w.Messages) >= webSocketSampleLimit {
+		return false
+	}
+	if len(msg.Payload) > webSocketMaxPayload {
+		// 文本消息按字符截断，二进制消息为 base64 编码，不受影响
+		msg.Payload = truncateUTF8(msg.Payload, webSocketMaxPayload)
+		msg.Truncated = true
+	}
+	key := msg.Direction + "\x00" + msg.Payload
+	if w.seen == nil {
+		w.seen = make(map[string]bool)
+	}
+	if w.seen[key] {
+		return false
+	}
+	w.seen[key] = true
+	w.Messages = append(w.Messages, msg)
+	return true
+}
+
+// webSocketConn 标签页中打开的 WebSocket 连接
+type webSocketConn struct {
+	url  string
+	page string
+	info *WebSocketInfo // 握手后获取，端点不在爬取范围内时为 nil
+}
+
+// OpenWebSocket 记录新建的 WebSocket 连接及所在页面
+func (ts *TabState) OpenWebSocket(id network.RequestID, rawURL string) {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+	if ts.webSockets == nil {
+		ts.webSockets = make(map[network.RequestID]*webSocketConn)
+	}
+	ts.webSockets[id] = &webSocketConn{url: rawURL, page: ts.currentReq.URL}
+}
+
+// GetWebSocket 获取 WebSocket 连接
+func (ts *TabState) GetWebSocket(id network.RequestID) (*webSocketConn, bool) {
+	ts.mu.RLock()
+	defer ts.mu.RUnlock()
+	conn, ok := ts.webSockets[id]
+	return conn, ok
+}
+
+// CloseWebSocket 移除已关闭的 WebSocket 连接
+func (ts *TabState) CloseWebSocket(id network.RequestID) {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+	delete(ts.webSockets, id)
+}
+
+// SaveWebSocket 保存 WebSocket 端点（握手请求），返回该端点的记录；端点不在爬取范围内时返回 nil
+func (rs *RequestStore) SaveWebSocket(page string, req request) *WebSocketInfo {
+	normalizedURL, err := normalizeURL(req.URL)
+	if err != nil {
+		return nil
+	}
+	rs.webSocketMu.Lock()
+	defer rs.webSocketMu.Unlock()
+	info, ok := rs.webSockets[normalizedURL]
+	if !ok {
+		info = &WebSocketInfo{}
+	}
+	req.WebSocket = info
+	if !rs.SaveRequestFrom(page, req) && !ok {
+		return nil
+	}
+	if !ok {
+		if rs.webSockets == nil {
+			rs.webSockets = make(map[string]*WebSocketInfo)
+		}
+		rs.webSockets[normalizedURL] = info
+	}
+	info.addPage(page)
+	return info
+}
+
+// SaveWebSocketMessage 采样 WebSocket 消息，新采样的消息同时写入持久化后端
+func (rs *RequestStore) SaveWebSocketMessage(rawURL string, info *WebSocketInfo, msg WebSocketMessage) {
+	if !info.addMessage(msg) {
+		return
+	}
+	if backend := rs.getBackend(); backend != nil {
+		if normalizedURL, err := normalizeURL(rawURL); err == nil {
+			_ = backend.SaveWebSocketMessage(normalizedURL, msg)
+		}
+	}
+}
+
+// handleWebSocketEvent 处理 WebSocket 事件：记录端点、握手请求头和响应，采样收发的消息
+// 在事件监听器中同步处理，保证同一连接的事件按顺序记录，连接的 info 字段只在监听器中读写
+func handleWebSocketEvent(ev interface{}, tabState *TabState, store *RequestStore) {
+	switch ev := ev.(type) {
+	case *network.EventWebSocketCreated:
+		tabState.OpenWebSocket(ev.RequestID, ev.URL)
+	case *network.EventWebSocketWillSendHandshakeRequest:
+		conn, ok := tabState.GetWebSocket(ev.RequestID)
+		if !ok || ev.Request == nil {
+			return
+		}
+		req := geneRequest("GET", conn.url, ev.Request.Headers, "", "websocket")
+		conn.info = store.SaveWebSocket(conn.page, req)
+	case *network.EventWebSocketHandshakeResponseReceived:
+		if conn, ok := tabState.GetWebSocket(ev.RequestID); ok && conn.info != nil && ev.Response != nil {
+			conn.info.setHandshake(ev.Response.Status, ev.Response.Headers)
+			store.SaveResponse(responseRecord{
+				URL:          conn.url,
+				ResourceType: network.ResourceTypeWebSocket.String(),
+				Status:       ev.Response.Status,
+				Headers:      ev.Response.Headers,
+			})
+		}
+	case *network.EventWebSocketFrameSent:
+		recordWebSocketFrame(tabState, store, ev.RequestID, "sent", ev.Response)
+	case *network.EventWebSocketFrameReceived:
+		recordWebSocketFrame(tabState, store, ev.RequestID, "received", ev.Response)
+	case *network.EventWebSocketClosed:
+		tabState.CloseWebSocket(ev.RequestID)
+	}
+}
+
+// recordWebSocketFrame 采样数据帧，忽略控制帧（ping、pong、close）
+func recordWebSocketFrame(tabState *TabState, store *RequestStore, id network.RequestID, direction string, frame *network.WebSocketFrame) {
+	if frame == nil || webSocketSampleLimit <= 0 {
+		return
+	}
+	opcode := int(frame.Opcode)
+	if opcode != 1 && opcode != 2 {
+		return
+	}
+	conn, ok := tabState.GetWebSocket(id)
+	if !ok || conn.info == nil {
+		return
+	}
+	store.SaveWebSocketMessage(conn.url, conn.info, WebSocketMessage{Direction: direction, Opcode: opcode, Payload: frame.PayloadData})
+}
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestWebSocketInfoAddMessage(t *testing.T) {
+	info := &WebSocketInfo{}
+	long := strings.Repeat("中", webSocketMaxPayload) // 每个字符 3 字节
+	messages := []WebSocketMessage{
+		{Direction: "sent", Opcode: 1, Payload: "ping"},
+		{Direction: "sent", Opcode: 1, Payload: "ping"},
+		{Direction: "received", Opcode: 1, Payload: "ping"},
+		{Direction: "received", Opcode: 1, Payload: long},
+	}
+	for _, msg := range messages {
+		info.addMessage(msg)
+	}
+	if info.MessageCount != 4 || len(info.Messages) != 3 {
+		t.Fatalf("MessageCount = %d, samples = %d, want 4, 3", info.MessageCount, len(info.Messages))
+	}
+	truncated := info.Messages[2]
+	if !truncated.Truncated || len(truncated.Payload) > webSocketMaxPayload || !utf8.ValidString(truncated.Payload) {
+		t.Errorf("truncated payload: %d bytes, valid UTF-8 %v, truncated %v", len(truncated.Payload), utf8.ValidString(truncated.Payload), truncated.Truncated)
+	}
+
+	data, err := json.Marshal(request{Method: "GET", URL: "wss://example.com/ws", WebSocket: info})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded request
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if decoded.WebSocket == nil || decoded.WebSocket.MessageCount != 4 || len(decoded.WebSocket.Messages) != 3 {
+		t.Errorf("round trip = %s", data)
+	}
+}
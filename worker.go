@@ -0,0 +1,218 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/chromedp/cdproto/cdp"
+	"github.com/chromedp/cdproto/fetch"
+	"github.com/chromedp/cdproto/network"
+	"github.com/chromedp/cdproto/target"
+	"github.com/chromedp/chromedp"
+)
+
+// workerRegistry 一次爬取中附加的 Worker 目标，共享 Worker 和 Service Worker 对所有标签页可见，只附加一次
+type workerRegistry struct {
+	mu       sync.Mutex
+	ctx      context.Context // 共享 Worker 和 Service Worker 的父上下文，爬取结束时取消
+	cancel   context.CancelFunc
+	attached map[target.ID]bool
+	cancels  map[target.ID]context.CancelFunc // 已附加 Worker 的上下文取消函数，目标销毁或分离时调用
+	sessions map[target.SessionID]target.ID   // 标签页自动附加的专用 Worker 会话
+	active   *TabState                        // 最近导航的标签页
+}
+
+// newWorkerRegistry 创建 Worker 注册表，ctx 为本次爬取的第一个标签页上下文
+func newWorkerRegistry(ctx context.Context) *workerRegistry {
+	ctx, cancel := context.WithCancel(ctx)
+	return &workerRegistry{
+		ctx:      ctx,
+		cancel:   cancel,
+		attached: make(map[target.ID]bool),
+		cancels:  make(map[target.ID]context.CancelFunc),
+		sessions: make(map[target.SessionID]target.ID),
+	}
+}
+
+// close 关闭本次爬取附加的共享 Worker 和 Service Worker
+func (r *workerRegistry) close() {
+	r.cancel()
+}
+
+// markAttached 记录 Worker 目标，已附加过时返回 false
+func (r *workerRegistry) markAttached(id target.ID) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.attached[id] {
+		return false
+	}
+	r.attached[id] = true
+	return true
+}
+
+// setCancel 记录 Worker 上下文的取消函数，目标已销毁时直接取消
+func (r *workerRegistry) setCancel(id target.ID, cancel context.CancelFunc) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if !r.attached[id] {
+		cancel()
+		return
+	}
+	r.cancels[id] = cancel
+}
+
+// addSession 记录标签页自动附加的 Worker 会话，分离事件只携带会话 ID
+func (r *workerRegistry) addSession(sessionID target.SessionID, id target.ID) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.sessions[sessionID] = id
+}
+
+// detach 在 Worker 目标销毁后取消其上下文并移除记录，非 Worker 目标忽略
+func (r *workerRegistry) detach(id target.ID) {
+	r.mu.Lock()
+	cancel := r.cancels[id]
+	delete(r.cancels, id)
+	delete(r.attached, id)
+	for sessionID, tid := range r.sessions {
+		if tid == id {
+			delete(r.sessions, sessionID)
+		}
+	}
+	r.mu.Unlock()
+	if cancel != nil {
+		cancel()
+	}
+}
+
+// detachSession 在自动附加的会话分离后取消对应 Worker 的上下文
+func (r *workerRegistry) detachSession(sessionID target.SessionID) {
+	r.mu.Lock()
+	id, ok := r.sessions[sessionID]
+	r.mu.Unlock()
+	if ok {
+		r.detach(id)
+	}
+}
+
+// setActive 记录最近导航的标签页
+func (r *workerRegistry) setActive(tabState *TabState) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.active = tabState
+}
+
+// currentReq 获取 Worker 请求所属页面的请求：专用 Worker 属于所在标签页，
+// 共享 Worker 和 Service Worker 无法确定发起的页面，归属于最近导航的标签页
+func (r *workerRegistry) currentReq(info *target.Info, tabState *TabState) request {
+	if info.Type != "worker" {
+		r.mu.Lock()
+		if r.active != nil {
+			tabState = r.active
+		}
+		r.mu.Unlock()
+	}
+	return tabState.GetCurrentReq()
+}
+
+// isWorkerTarget 判断目标是否为 Web Worker、SharedWorker 或 Service Worker，忽略浏览器扩展的 Worker
+func isWorkerTarget(info *target.Info) bool {
+	if info == nil || strings.HasPrefix(info.URL, "chrome-extension://") {
+		return false
+	}
+	switch info.Type {
+	case "worker", "shared_worker", "service_worker":
+		return true
+	}
+	return false
+}
+
+// attachWorker 附加到 Worker 目标并开启与标签页相同的请求拦截，Worker 发出的请求记录为 worker 来源
+// 专用 Worker 随标签页关闭；共享 Worker 和 Service Worker 可能被其它标签页使用，不随标签页关闭，在本次爬取结束时关闭
+// Worker 启动时在附加前发出的请求（如 importScripts）无法拦截
+func attachWorker(tabCtx context.Context, info *target.Info, tabState *TabState, workers *workerRegistry, store *RequestStore, conf *TabConfig) {
+	if !workers.markAttached(info.TargetID) {
+		return
+	}
+	current := workers.currentReq(info, tabState)
+	// Worker 脚本本身
+	store.SaveRequestFrom(current.URL, geneRequest("GET", info.URL, current.Headers, "", "worker"))
+
+	parent := tabCtx
+	if info.Type != "worker" {
+		parent = workers.ctx
+	}
+	// 取消时会关闭 Worker 目标，在目标销毁、分离或父上下文结束时取消
+	workerCtx, cancel := chromedp.NewContext(parent, chromedp.WithTargetID(info.TargetID))
+	workers.setCancel(info.TargetID, cancel)
+	chromedp.ListenTarget(workerCtx, func(ev interface{}) {
+		switch ev := ev.(type) {
+		case *fetch.EventRequestPaused:
+			// 请求所属页面在请求时确定，标签页可能已导航到其它页面
+			go handleWorkerRequestPaused(workerCtx, ev, workers.currentReq(info, tabState).URL, store, conf)
+		case *fetch.EventAuthRequired:
+			go func() {
+				c := chromedp.FromContext(workerCtx)
+				if err := continueWithAuth(cdp.WithExecutor(workerCtx, c.Target), ev, conf.Session); err != nil {
+					GetGlobalLogger().ErrorWithURL("Failed to answer auth challenge", ev.Request.URL, err)
+				}
+			}()
+		}
+	})
+	if err := chromedp.Run(workerCtx, fetch.Enable().WithHandleAuthRequests(handleAuthRequests(conf.Session))); err != nil {
+		GetGlobalLogger().Debug(fmt.Sprintf("Failed to attach to %s %s: %v", info.Type, info.URL, err))
+		workers.detach(info.TargetID)
+		return
+	}
+	GetGlobalLogger().Debug(fmt.Sprintf("Attached to %s %s", info.Type, info.URL))
+}
+
+// handleWorkerRequestPaused 处理 Worker 中被拦截的请求：按拦截策略处理，记录后放行
+func handleWorkerRequestPaused(ctx context.Context, ev *fetch.EventRequestPaused, page string, store *RequestStore, conf *TabConfig) {
+	c := chromedp.FromContext(ctx)
+	targetCtx := cdp.WithExecutor(ctx, c.Target)
+	if isResponseStage(ev) {
+		_ = continueResponse(targetCtx, ev)
+		return
+	}
+
+	var postData string
+	if ev.Request.HasPostData && ev.NetworkID != "" {
+		if data, err := network.GetRequestPostData(ev.NetworkID).Do(targetCtx); err == nil {
+			postData = data
+		}
+	}
+	resourceType := ev.ResourceType.String()
+	source := "worker"
+	if resourceType == "EventSource" {
+		source = "eventsource"
+	}
+	newReq := geneRequest(ev.Request.Method, ev.Request.URL, ev.Request.Headers, postData, source)
+
+	// 匹配模拟响应规则的请求不会发出，跳过拦截策略和登出过滤
+	if rewriteConfig.hasMock(ev) {
+		store.SaveRequestFrom(page, newReq)
+		_ = continuePaused(targetCtx, ev, conf)
+		return
+	}
+
+	switch action, _ := conf.Policy.Match(resourceType, ev.Request.URL); action {
+	case PolicyBlock:
+		_ = fetch.FailRequest(ev.RequestID, network.ErrorReasonBlockedByClient).Do(targetCtx)
+		return
+	case PolicyRecord:
+		newReq.Blocked = true
+		store.SaveRequestFrom(page, newReq)
+		_ = fetch.FailRequest(ev.RequestID, network.ErrorReasonBlockedByClient).Do(targetCtx)
+		return
+	}
+
+	if strings.Contains(strings.ToLower(ev.Request.URL), "logout") {
+		_ = fetch.FailRequest(ev.RequestID, network.ErrorReasonAborted).Do(targetCtx)
+		return
+	}
+	store.SaveRequestFrom(page, newReq)
+	_ = continuePaused(targetCtx, ev, conf)
+}
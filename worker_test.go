@@ -0,0 +1,98 @@
+package main
+
+import (
+	"context"
+	"testing"
+
+	"github.com/chromedp/cdproto/target"
+)
+
+func TestIsWorkerTarget(t *testing.T) {
+	tests := []struct {
+		info *target.Info
+		want bool
+	}{
+		{nil, false},
+		{&target.Info{Type: "worker", URL: "https://example.com/worker.js"}, true},
+		{&target.Info{Type: "shared_worker", URL: "https://example.com/shared.js"}, true},
+		{&target.Info{Type: "service_worker", URL: "https://example.com/sw.js"}, true},
+		{&target.Info{Type: "service_worker", URL: "chrome-extension://abcdef/background.js"}, false},
+		{&target.Info{Type: "page", URL: "https://example.com/"}, false},
+	}
+	for _, tt := range tests {
+		if got := isWorkerTarget(tt.info); got != tt.want {
+			t.Errorf("isWorkerTarget(%+v) = %v, want %v", tt.info, got, tt.want)
+		}
+	}
+}
+
+func TestWorkerRegistry(t *testing.T) {
+	workers := newWorkerRegistry(context.Background())
+	if !workers.markAttached("a") || workers.markAttached("a") {
+		t.Error("worker attached twice")
+	}
+	if !newWorkerRegistry(context.Background()).markAttached("a") {
+		t.Error("attach state leaked across crawls")
+	}
+
+	owner, other := &TabState{}, &TabState{}
+	owner.UpdateRequestState(request{URL: "https://example.com/a"})
+	other.UpdateRequestState(request{URL: "https://example.com/b"})
+	dedicated := &target.Info{Type: "worker"}
+	shared := &target.Info{Type: "shared_worker"}
+	if got := workers.currentReq(shared, owner).URL; got != "https://example.com/a" {
+		t.Errorf("shared worker page without active tab = %s", got)
+	}
+	workers.setActive(other)
+	if got := workers.currentReq(dedicated, owner).URL; got != "https://example.com/a" {
+		t.Errorf("dedicated worker page = %s", got)
+	}
+	if got := workers.currentReq(shared, owner).URL; got != "https://example.com/b" {
+		t.Errorf("shared worker page = %s", got)
+	}
+	owner.UpdateRequestState(request{URL: "https://example.com/c"})
+	if got := workers.currentReq(dedicated, owner).URL; got != "https://example.com/c" {
+		t.Errorf("dedicated worker page after navigation = %s", got)
+	}
+
+	workers.close()
+	if workers.ctx.Err() == nil {
+		t.Error("worker context not canceled")
+	}
+}
+
+func TestWorkerRegistryDetach(t *testing.T) {
+	workers := newWorkerRegistry(context.Background())
+
+	// 目标销毁时取消
+	workers.markAttached("a")
+	ctxA, cancelA := context.WithCancel(context.Background())
+	workers.setCancel("a", cancelA)
+	workers.detach("a")
+	if ctxA.Err() == nil {
+		t.Error("worker context not canceled on target destroyed")
+	}
+
+	// 自动附加的会话分离时取消
+	workers.markAttached("b")
+	workers.addSession("session-b", "b")
+	ctxB, cancelB := context.WithCancel(context.Background())
+	workers.setCancel("b", cancelB)
+	workers.detachSession("session-b")
+	if ctxB.Err() == nil {
+		t.Error("worker context not canceled on session detached")
+	}
+
+	// 设置取消函数前目标已销毁
+	workers.markAttached("c")
+	workers.detach("c")
+	ctxC, cancelC := context.WithCancel(context.Background())
+	workers.setCancel("c", cancelC)
+	if ctxC.Err() == nil {
+		t.Error("worker context not canceled after early destroy")
+	}
+
+	if len(workers.attached) != 0 || len(workers.cancels) != 0 || len(workers.sessions) != 0 {
+		t.Errorf("registry not cleaned up: %d attached, %d cancels, %d sessions", len(workers.attached), len(workers.cancels), len(workers.sessions))
+	}
+}